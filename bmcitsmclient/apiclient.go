@@ -1,35 +1,63 @@
-package checkptclient
+//Package bmcitsmclient is a client for the BMC Remedy AR REST API,
+//logging in with an AR-JWT token and creating and getting form
+//entries.
+package bmcitsmclient
 
 import (
-	"crypto/tls"
-	"crypto/x509"
-	"encoding/json"
-	"flag"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
+	"path"
 	"time"
 
-	"github.com/ericroys/bmcitsmclient/rest"
+	"github.com/ericroys/rest"
 )
 
 const (
 	endpointLogin = `jwt/login`
+	endpointEntry = `arsys/v1/entry`
 )
 
 //APIConfig provides the construct for configuring the
-//CheckPoint APIClient
+//Remedy APIClient
 type APIConfig struct {
 	//User, Pwd string
 	Baseurl  string
 	User     string
 	Pass     string
 	CertPath string
+	//TLS configures trusted CAs, client certificates for mutual
+	//TLS, certificate pinning and other TLS settings
+	TLS rest.TLSOptions
+	//Transport, when set, sends the requests in place of the
+	//transport built from TLS, e.g. a rest.Recorder replaying
+	//recorded exchanges in tests
+	Transport http.RoundTripper
+	//Timeout is the default deadline applied to each operation
+	//when the caller's context does not already carry one
+	Timeout time.Duration
+	//Retry is the policy for resending failed requests, nil
+	//disables retries
+	Retry *rest.RetryPolicy
+	//Middleware is added to every request sent by the client,
+	//e.g. for logging, metrics or header injection
+	Middleware []rest.Middleware
+}
+
+//SetMaxRetries sets the number of times a failed request is retried
+//using exponential backoff. Zero disables retries.
+func (ac *APIConfig) SetMaxRetries(retries int) {
+	if retries <= 0 {
+		ac.Retry = nil
+		return
+	}
+	ac.Retry = rest.NewRetryPolicy(retries)
 }
 
 //NewAPIConfig creates and initializes an APIConfig object.
-//Defaults to use the last session for the user
+//Failed requests are not retried unless enabled with
+//SetMaxRetries or Retry.
 func NewAPIConfig(baseurl, user, pass, certpath string) *APIConfig {
 
 	ac := APIConfig{
@@ -37,122 +65,117 @@ func NewAPIConfig(baseurl, user, pass, certpath string) *APIConfig {
 		Pass:     pass,
 		Baseurl:  baseurl,
 		CertPath: certpath,
+		Timeout:  20 * time.Second,
 	}
 	return &ac
 }
 
-//APIClient is the CheckPoint API Client. All interaction with
-//a Check Point service is done using methods provided by this
+//APIClient is the Remedy AR REST API Client. All interaction with
+//a Remedy service is done using methods provided by this
 //client.
 type APIClient struct {
-	conf        *APIConfig
-	httpClient  *http.Client
-	token       string
-	nextRefresh time.Time
+	conf       *APIConfig
+	httpClient *http.Client
+	//auth holds the AR-JWT token, logging in on first
+	//use and again when the token expires
+	auth *rest.AuthSession
 }
 
-func (a *APIClient) getToken() error {
-	n := time.Now()
-	if a.token == "" || n.After(a.nextRefresh) {
-		err := a.Login()
-		if err != nil {
-			return err
-		}
-	}
-	if a.token == "" {
-		return fmt.Errorf("unable to obtain the auth token")
-	}
-	return nil
+//Login logs into the Remedy service and
+//obtains the AR-JWT token used to authenticate
+//further requests. Calling Login is optional,
+//the client logs in as needed.
+func (a *APIClient) Login(ctx context.Context) error {
+	return a.auth.Refresh(ctx)
 }
 
-//Login logs into the Check Point service and
-//returns a session identifier and session timeout
-//to the client
-func (a *APIClient) Login() error {
-	//var resp LoginResponse
-	//l := a.conf.session
+//login logs into the Remedy service and returns the
+//AR-JWT token and how long it is used for
+func (a *APIClient) login(ctx context.Context) (string, time.Duration, error) {
 	uri, err := a.getPath(endpointLogin, "")
 	if err != nil {
-		return err
+		return "", 0, err
 	}
-	msg := fmt.Sprintf("username=%s&password=%s", a.conf.User, a.conf.Pass)
+	msg := url.Values{}
+	msg.Set("username", a.conf.User)
+	msg.Set("password", a.conf.Pass)
+
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
 	s, err := rest.NewRequestBuilder(uri, a.httpClient).
+		Context(ctx).
+		Retry(a.conf.Retry).
+		Use(a.conf.Middleware...).
 		Auth(rest.AuthNoAuth{}).
-		Header("Accept", "application/x-www-form-urlencoded").
-		Message([]byte(msg)).
+		ContentType("application/x-www-form-urlencoded").
+		Message([]byte(msg.Encode())).
 		Method(rest.POST).
-		//ErrorHandler(ErrHandler{}).
+		ErrorHandler(ErrHandler{}).
 		Build()
 
 	if err != nil {
-		return err
+		return "", 0, err
 	}
 
 	data, err := s.Send()
 	if err != nil {
-		return err
+		return "", 0, err
 	}
 
-	a.token = string(data)
-
-	//pad in a 5 second buffer for the timeout
-	a.nextRefresh = time.Now().Add(
-		time.Duration(60 * time.Second))
-	return nil
+	//renew the token every minute, an expired token
+	//is also renewed when the service rejects it
+	return string(data), 60 * time.Second, nil
 }
 
-//CreateHost creates a Host on the CheckPoint service
-func (a *APIClient) CreateHost(host Host) (Host, error) {
-	var h Host
-	uri, err := a.getPath(endpointAddHost, "")
+//CreateEntry creates an entry on a Remedy form and returns
+//the id of the new entry taken from the Location header
+func (a *APIClient) CreateEntry(ctx context.Context, form string, entry Entry) (string, error) {
+	uri, err := a.getPath(endpointEntry, url.PathEscape(form))
 	if err != nil {
-		return h, err
+		return "", err
 	}
 
-	err = a.send(uri, &host, &h, true)
+	_, resp, err := send[Entry, NoMessage](ctx, a, rest.POST, uri, entry, true)
 	if err != nil {
-		return h, err
+		return "", err
 	}
-	return h, nil
+	loc := resp.Location()
+	if len(loc) == 0 {
+		return "", fmt.Errorf("no Location returned for new entry on form [%s]", form)
+	}
+	return path.Base(loc), nil
 }
 
-func (a *APIClient) Publish() error {
-
-	var msg NoMessage
-	uri, err := a.getPath(endpointPublish, "")
+//GetEntry returns an entry from a Remedy form by entry id
+func (a *APIClient) GetEntry(ctx context.Context, form, id string) (Entry, error) {
+	var e Entry
+	uri, err := a.getPath(endpointEntry, url.PathEscape(form)+"/"+url.PathEscape(id))
 	if err != nil {
-		return err
+		return e, err
 	}
 
-	err = a.send(uri, &msg, &msg, true)
-	if err != nil {
-		return err
-	}
-	return nil
+	e, _, err = get[Entry](ctx, a, rest.GET, uri, true)
+	return e, err
 }
 
-func (a *APIClient) getSender(uri string, msg []byte, auth bool) (*rest.Request, error) {
+//getBuilder returns a RequestableBuilder for a Remedy endpoint,
+//adding the AR-JWT token when auth is set
+func (a *APIClient) getBuilder(ctx context.Context, method rest.HTTPMethod, uri string, auth bool) (*rest.RequestableBuilder, error) {
 
 	builder := rest.NewRequestBuilder(uri, a.httpClient).
+		Context(ctx).
+		Retry(a.conf.Retry).
+		Use(a.conf.Middleware...).
 		Auth(rest.AuthNoAuth{}).
-		Header("Accept", "application/json").
-		Message(msg).
-		Method(rest.POST).
+		Method(method).
 		ErrorHandler(ErrHandler{})
 
-	//if auth flag then need to get the session id to pass in the
-	//header.
+	//if auth flag then the token is passed in the
+	//header, logging in if needed
 	if auth {
-		//make sure we have a current sid
-		if err := a.getToken(); err != nil {
-			return nil, err
-		}
-		//set the header with current sid
-		builder.Header("X-chkp-sid", a.sid)
+		builder.Auth(a.auth)
 	}
-
-	v, err := builder.Build()
-	return v, err
+	return builder, nil
 }
 
 //NewClient initializes and validates a new APIClient provided
@@ -163,50 +186,32 @@ func NewClient(conf *APIConfig) (*APIClient, error) {
 		return nil, err
 	}
 
-	//customize client so set timeout and connection pool stuff
-	trans := &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 100,
-	}
-
-	//get certificate stuff
-	if len(conf.CertPath) > 0 {
-		p, err := buildCertPool(conf.CertPath)
+	//customize client so set tls and connection pool stuff unless
+	//a transport is supplied, CertPath is trusted in addition to
+	//any TLS.CAFiles
+	trans := conf.Transport
+	if trans == nil {
+		opts := conf.TLS
+		if len(conf.CertPath) > 0 {
+			opts.CAFiles = append([]string{conf.CertPath}, opts.CAFiles...)
+		}
+		t, err := opts.Transport()
 		if err != nil {
 			return nil, err
 		}
-		trans.TLSClientConfig = p
+		trans = t
 	}
-	return &APIClient{
+
+	//no client wide timeout, deadlines are applied per operation
+	//via context (see APIConfig.Timeout)
+	a := &APIClient{
 		conf: conf,
 		httpClient: &http.Client{
 			Transport: trans,
-			Timeout:   20 * time.Second,
 		},
-	}, nil
-}
-
-//buildCertPool
-func buildCertPool(crtpath string) (*tls.Config, error) {
-	cert, err := ioutil.ReadFile(crtpath)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to read certificate file %s", crtpath)
 	}
-
-	//for self signed certs
-	insecure := flag.Bool("insecure-ssl", false, "Accept/Ignore all server SSL certificates")
-	flag.Parse()
-
-	certPool, _ := x509.SystemCertPool()
-	if certPool == nil {
-		certPool = x509.NewCertPool()
-	}
-	certPool.AppendCertsFromPEM(cert)
-
-	return &tls.Config{
-		InsecureSkipVerify: *insecure,
-		RootCAs:            certPool,
-	}, nil
+	a.auth = rest.NewAuthSession("Authorization", "AR-JWT ", a.login)
+	return a, nil
 }
 
 //checks if url is valid, errors of not
@@ -243,49 +248,44 @@ func (a *APIClient) getPath(uri string, id string) (string, error) {
 	return rurl, nil
 }
 
-//getMessage accepts an interface to convert
-//to []byte to be used as message sent to server
-func getMessage(i interface{}) ([]byte, error) {
-
-	t, err := json.Marshal(&i)
-	if err != nil {
-		return nil, fmt.Errorf("Unable to create json message. %s", err)
+//withTimeout applies the configured default timeout to ctx unless
+//the caller already set a deadline
+func (a *APIClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	if t == nil || len(t) < 1 {
-		return nil, fmt.Errorf("Unable to create json message. No message content")
+	if _, ok := ctx.Deadline(); ok || a.conf.Timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
-	return t, nil
+	return context.WithTimeout(ctx, a.conf.Timeout)
 }
 
-func getResponse(data []byte, i interface{}) error {
-	err := json.Unmarshal(data, &i)
+//send sends msg to the Remedy endpoint at url and transforms
+//the response into a Resp. The full response is returned so
+//callers can make use of the status code and headers
+func send[Req, Resp any](ctx context.Context, a *APIClient, method rest.HTTPMethod, url string, msg Req, auth bool) (Resp, *rest.Response, error) {
+
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+
+	var out Resp
+	b, err := a.getBuilder(ctx, method, url, auth)
 	if err != nil {
-		return fmt.Errorf("failed to transform response message. %v", err)
+		return out, nil, err
 	}
-	return nil
+	return rest.DoJSON[Req, Resp](ctx, b, msg)
 }
 
-func (a *APIClient) send(url string, msg interface{}, resp interface{}, auth bool) error {
+//get is send for requests without a message
+func get[Resp any](ctx context.Context, a *APIClient, method rest.HTTPMethod, url string, auth bool) (Resp, *rest.Response, error) {
 
-	//build message
-	toMsg, err := getMessage(&msg)
-	fmt.Println(string(toMsg))
-	if err != nil {
-		return err
-	}
-	s, err := a.getSender(url, toMsg, auth)
-	if err != nil {
-		return err
-	}
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
 
-	data, err := s.Send()
+	var out Resp
+	b, err := a.getBuilder(ctx, method, url, auth)
 	if err != nil {
-		return err
+		return out, nil, err
 	}
-	//log.Printf("data before response trans: %s", string(data))
-	err = getResponse(data, &resp)
-	if err != nil {
-		return err
-	}
-	return nil
+	return rest.GetJSON[Resp](ctx, b)
 }
@@ -0,0 +1,113 @@
+package bmcitsmclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ericroys/bmcitsmclient/fake"
+	"github.com/ericroys/rest"
+)
+
+func testServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/jwt/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			t.Errorf("Unexpected content type: %s", r.Header.Get("Content-Type"))
+		}
+		r.ParseForm()
+		if r.Form.Get("username") != "Demo" || r.Form.Get("password") != "p&ss" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`[{"messageType":"ERROR","messageText":"Authentication failed","messageNumber":623}]`))
+			return
+		}
+		w.Write([]byte("token123"))
+	})
+	mux.HandleFunc("/api/arsys/v1/entry/HPD:IncidentInterface_Create", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "AR-JWT token123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if len(b) == 0 {
+			t.Error("Expected entry message")
+		}
+		w.Header().Set("Location", "http://"+r.Host+"/api/arsys/v1/entry/HPD:IncidentInterface_Create/000000000000101")
+		w.WriteHeader(http.StatusCreated)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCreateEntry(t *testing.T) {
+	ts := testServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(NewAPIConfig(ts.URL+"/api", "Demo", "p&ss", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := c.CreateEntry(context.Background(), "HPD:IncidentInterface_Create", Entry{
+		Values: map[string]interface{}{"Description": "printer on fire"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "000000000000101" {
+		t.Fatalf("Unexpected entry id: %s", id)
+	}
+}
+
+func TestLoginFailed(t *testing.T) {
+	ts := testServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(NewAPIConfig(ts.URL+"/api", "Demo", "wrong", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Login(context.Background())
+	if !rest.IsUnauthorized(err) {
+		t.Fatalf("Expected unauthorized, got: %v", err)
+	}
+}
+
+func TestFakeEntry(t *testing.T) {
+	srv := fake.NewServer("Demo", "P@ssw0rd")
+	defer srv.Close()
+
+	c, err := NewClient(NewAPIConfig(srv.BaseURL(), "Demo", "P@ssw0rd", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	id, err := c.CreateEntry(ctx, fake.IncidentInterface, Entry{
+		Values: map[string]interface{}{
+			"Description": "printer on fire",
+			"Impact":      "1-Extensive/Widespread",
+			"Urgency":     "1-Critical",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//an expired token is renewed
+	srv.ExpireTokens()
+	e, err := c.GetEntry(ctx, fake.IncidentInterface, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Values["Incident Number"] != "INC000000000001" || e.Values["Submitter"] != "Demo" {
+		t.Fatalf("Unexpected entry: %v", e.Values)
+	}
+
+	_, err = c.GetEntry(ctx, fake.IncidentInterface, "000000000000999")
+	if !rest.IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+	if he, ok := err.(*rest.HTTPError); !ok || he.Code != "302" {
+		t.Fatalf("Expected Remedy error 302, got: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package bmcitsmclient
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/ericroys/rest"
+)
+
+//ErrHandler is a rest.ErrorHandler for the Remedy service.
+//Unsuccessful responses are returned as *rest.HTTPError with the
+//Remedy message number and message text.
+type ErrHandler struct{}
+
+//Handle implements rest.ErrorHandler
+func (eh ErrHandler) Handle(code int, data []byte) error {
+
+	if code >= 200 && code < 300 {
+		return nil
+	}
+	he := &rest.HTTPError{
+		StatusCode: code,
+		Body:       data,
+	}
+	//no special handling based on body
+	if len(data) < 1 {
+		return he
+	}
+	var e []ErrMessage
+	json.Unmarshal(data, &e)
+
+	log.Printf("error handler: %+v", e)
+	var f []string
+	for _, m := range e {
+		t := m.MessageText
+		if len(m.MessageAppendedText) > 0 {
+			t = t + " " + m.MessageAppendedText
+		}
+		f = append(f, t)
+	}
+	if len(e) > 0 {
+		he.Code = strconv.Itoa(e[0].MessageNumber)
+	}
+	he.Message = strings.Join(f, "\n")
+	return he
+}
@@ -0,0 +1,327 @@
+package fake
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//qualification is a parsed Remedy qualification, reporting whether
+//an entry's values match
+type qualification func(values map[string]interface{}) bool
+
+//token kinds of the qualification lexer
+const (
+	tokField = iota
+	tokString
+	tokNumber
+	tokNull
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokOpen
+	tokClose
+	tokEnd
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+//lex splits a qualification such as
+//  'Status' < 4 AND ('Urgency' = "1-Critical" OR 'Description' LIKE "%fire%")
+//into tokens
+func lex(q string) ([]token, error) {
+	var toks []token
+	rs := []rune(q)
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			toks = append(toks, token{tokOpen, "("})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokClose, ")"})
+			i++
+		case c == '\'' || c == '"':
+			//quoted field name or string, a doubled quote
+			//is a literal quote
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(rs); j++ {
+				if rs[j] == c {
+					if j+1 < len(rs) && rs[j+1] == c {
+						sb.WriteRune(c)
+						j++
+						continue
+					}
+					break
+				}
+				sb.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated quote at %d", i)
+			}
+			kind := tokString
+			if c == '\'' {
+				kind = tokField
+			}
+			toks = append(toks, token{kind, sb.String()})
+			i = j + 1
+		case strings.ContainsRune("=!<>", c):
+			op := string(c)
+			if i+1 < len(rs) && rs[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected ! at %d", i)
+			}
+			toks = append(toks, token{tokOp, op})
+			i += len(op)
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !strings.ContainsRune("()=!<>'\"", rs[j]) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected %c at %d", c, i)
+			}
+			w := string(rs[i:j])
+			switch strings.ToUpper(w) {
+			case "AND":
+				toks = append(toks, token{tokAnd, w})
+			case "OR":
+				toks = append(toks, token{tokOr, w})
+			case "NOT":
+				toks = append(toks, token{tokNot, w})
+			case "LIKE":
+				toks = append(toks, token{tokOp, "LIKE"})
+			case "$NULL$":
+				toks = append(toks, token{tokNull, w})
+			default:
+				if _, err := strconv.ParseFloat(w, 64); err != nil {
+					return nil, fmt.Errorf("unexpected %s at %d", w, i)
+				}
+				toks = append(toks, token{tokNumber, w})
+			}
+			i = j
+		}
+	}
+	return append(toks, token{tokEnd, ""}), nil
+}
+
+//parser is a recursive descent parser for qualifications
+type parser struct {
+	toks []token
+	pos  int
+}
+
+//parseQualification parses a Remedy qualification. An empty
+//qualification matches every entry.
+func parseQualification(q string) (qualification, error) {
+	if len(strings.TrimSpace(q)) == 0 {
+		return func(map[string]interface{}) bool { return true }, nil
+	}
+	toks, err := lex(q)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEnd {
+		return nil, fmt.Errorf("unexpected %s", p.peek().text)
+	}
+	return f, nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) or() (qualification, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		a, b := l, r
+		l = func(v map[string]interface{}) bool { return a(v) || b(v) }
+	}
+	return l, nil
+}
+
+func (p *parser) and() (qualification, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		a, b := l, r
+		l = func(v map[string]interface{}) bool { return a(v) && b(v) }
+	}
+	return l, nil
+}
+
+func (p *parser) not() (qualification, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		f, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(v map[string]interface{}) bool { return !f(v) }, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (qualification, error) {
+	if p.peek().kind == tokOpen {
+		p.next()
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokClose {
+			return nil, fmt.Errorf("missing )")
+		}
+		return f, nil
+	}
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, fmt.Errorf("expected operator, got %s", op.text)
+	}
+	r, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	var like *regexp.Regexp
+	if op.text == "LIKE" {
+		if r.kind != tokString {
+			return nil, fmt.Errorf("LIKE requires a string pattern")
+		}
+		pat := regexp.QuoteMeta(r.text)
+		pat = strings.NewReplacer("%", ".*", "_", ".").Replace(pat)
+		like = regexp.MustCompile("^" + pat + "$")
+	}
+	return func(v map[string]interface{}) bool {
+		a, b := l.value(v), r.value(v)
+		if like != nil {
+			return a != nil && like.MatchString(fmt.Sprint(a))
+		}
+		return compare(a, b, op.text)
+	}, nil
+}
+
+//operand is a field reference or literal in a comparison
+type operand token
+
+func (p *parser) operand() (operand, error) {
+	t := p.next()
+	switch t.kind {
+	case tokField, tokString, tokNumber, tokNull:
+		return operand(t), nil
+	}
+	return operand{}, fmt.Errorf("expected field or value, got %s", t.text)
+}
+
+//value resolves the operand against an entry's values
+func (o operand) value(v map[string]interface{}) interface{} {
+	switch o.kind {
+	case tokField:
+		return v[o.text]
+	case tokNumber:
+		f, _ := strconv.ParseFloat(o.text, 64)
+		return f
+	case tokNull:
+		return nil
+	}
+	return o.text
+}
+
+//compare compares two values numerically when both are numbers and
+//as strings otherwise. Null only equals null.
+func compare(a, b interface{}, op string) bool {
+	if a == nil || b == nil {
+		switch op {
+		case "=":
+			return a == nil && b == nil
+		case "!=":
+			return a != nil || b != nil
+		}
+		return false
+	}
+	var c int
+	fa, aok := number(a)
+	fb, bok := number(b)
+	if aok && bok {
+		switch {
+		case fa < fb:
+			c = -1
+		case fa > fb:
+			c = 1
+		}
+	} else {
+		c = strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+	switch op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+//number returns v as a float64 if it is numeric
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,516 @@
+//Package fake provides an in-process fake of the BMC Remedy AR REST
+//API for testing Remedy integration code without a Remedy server.
+//
+//The fake implements jwt/login and jwt/logout and create, get,
+//query, update and delete of form entries. Forms and their entries
+//are kept in memory and errors are returned as Remedy message
+//arrays.
+//
+//  srv := fake.NewServer("Demo", "P@ssw0rd")
+//  defer srv.Close()
+//  srv.Seed(fake.IncidentInterface, map[string]interface{}{...})
+//  conf := bmcitsmclient.NewAPIConfig(srv.BaseURL(), "Demo", "P@ssw0rd", "")
+package fake
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Names of the forms available on a new Server
+const (
+	IncidentInterface    = "HPD:IncidentInterface"
+	InfrastructureChange = "CHG:Infrastructure Change"
+)
+
+//Form describes a form on the fake server
+type Form struct {
+	Name string
+	//Required are the fields that must be set when an
+	//entry is created
+	Required []string
+	//Defaults are values set on new entries when not given
+	Defaults map[string]interface{}
+	//IDField, when set, is given IDPrefix followed by the entry
+	//number on creation, e.g. "Incident Number"
+	IDField  string
+	IDPrefix string
+}
+
+//standardForms are the forms available on a new Server
+var standardForms = []Form{
+	{
+		Name:     IncidentInterface,
+		Required: []string{"Description", "Impact", "Urgency"},
+		Defaults: map[string]interface{}{"Status": "New"},
+		IDField:  "Incident Number",
+		IDPrefix: "INC",
+	},
+	{
+		Name:     InfrastructureChange,
+		Required: []string{"Description"},
+		Defaults: map[string]interface{}{"Change Request Status": "Draft"},
+		IDField:  "Infrastructure Change ID",
+		IDPrefix: "CRQ",
+	},
+}
+
+//Message is a Remedy error message. Errors are returned as an
+//array of messages.
+type Message struct {
+	MessageType         string `json:"messageType"`
+	MessageText         string `json:"messageText"`
+	MessageAppendedText string `json:"messageAppendedText,omitempty"`
+	MessageNumber       int    `json:"messageNumber"`
+}
+
+//arError is an error returned with the http status
+type arError struct {
+	status int
+	msg    Message
+}
+
+func errorf(status, number int, appended, format string, args ...interface{}) *arError {
+	return &arError{status, Message{
+		MessageType:         "ERROR",
+		MessageText:         fmt.Sprintf(format, args...),
+		MessageAppendedText: appended,
+		MessageNumber:       number,
+	}}
+}
+
+//errAuth is the error for a failed login or a missing or
+//invalid token
+var errAuth = errorf(http.StatusUnauthorized, 623, "", "Authentication failed")
+
+//form is a form and its entries by entry id
+type form struct {
+	Form
+	entries map[string]map[string]interface{}
+	next    int
+}
+
+//Server is a fake Remedy AR REST API server. It is safe for
+//concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	users  map[string]string
+	tokens map[string]string
+	forms  map[string]*form
+}
+
+//NewServer starts a Server accepting logins from user with pass,
+//with the HPD:IncidentInterface and CHG:Infrastructure Change forms.
+//The caller should call Close when finished.
+func NewServer(user, pass string) *Server {
+	s := &Server{
+		users:  map[string]string{user: pass},
+		tokens: make(map[string]string),
+		forms:  make(map[string]*form),
+	}
+	for _, f := range standardForms {
+		s.AddForm(f)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+//BaseURL returns the url of the REST API for use as the
+//client's base url
+func (s *Server) BaseURL() string {
+	return s.URL + "/api"
+}
+
+//AddUser adds a user who may log in
+func (s *Server) AddUser(user, pass string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[user] = pass
+}
+
+//AddForm adds a form, replacing any form with the same name
+//and its entries
+func (s *Server) AddForm(f Form) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := make(map[string]interface{}, len(f.Defaults))
+	for k, v := range f.Defaults {
+		d[k] = v
+	}
+	f.Defaults = d
+	s.forms[f.Name] = &form{Form: f, entries: make(map[string]map[string]interface{})}
+}
+
+//Seed creates entries on a form, submitted by the user Seed,
+//and returns their entry ids
+func (s *Server) Seed(name string, entries ...map[string]interface{}) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.forms[name]
+	if !ok {
+		return nil, fmt.Errorf("form %s does not exist", name)
+	}
+	var ids []string
+	for _, values := range entries {
+		id, e := f.create("Seed", values)
+		if e != nil {
+			return ids, fmt.Errorf("%s %s", e.msg.MessageText, e.msg.MessageAppendedText)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+//Entry returns a copy of the values of an entry
+func (s *Server) Entry(name, id string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.forms[name]
+	if !ok {
+		return nil, false
+	}
+	v, ok := f.entries[id]
+	return copyValues(v), ok
+}
+
+//ExpireTokens invalidates all tokens as if they had expired
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]string)
+}
+
+//response is a reply built while the server is locked and
+//written once it is unlocked
+type response struct {
+	status   int
+	location string
+	body     interface{}
+}
+
+//serve routes a request to the login, logout or entry handlers.
+//Request bodies are read and responses written without holding
+//the lock, so that a slow client does not hold up the others.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.EscapedPath()
+	switch {
+	case p == "/api/jwt/login" && r.Method == http.MethodPost:
+		s.login(w, r)
+	case p == "/api/jwt/logout" && r.Method == http.MethodPost:
+		if e := s.logout(r); e != nil {
+			writeError(w, e)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasPrefix(p, "/api/arsys/v1/entry/"):
+		var (
+			in   entryValues
+			derr error
+		)
+		if r.Method == http.MethodPost || r.Method == http.MethodPut {
+			derr = json.NewDecoder(r.Body).Decode(&in)
+		}
+		resp, e := s.entry(r, derr, in.Values, strings.Split(strings.TrimPrefix(p, "/api/arsys/v1/entry/"), "/"))
+		if e != nil {
+			writeError(w, e)
+			return
+		}
+		if len(resp.location) > 0 {
+			w.Header().Set("Location", resp.location)
+		}
+		if resp.body == nil {
+			w.WriteHeader(resp.status)
+			return
+		}
+		writeJSON(w, resp.status, resp.body)
+	default:
+		writeError(w, errorf(http.StatusNotFound, 0, p, "Resource not found"))
+	}
+}
+
+//entryValues is the message creating or updating an entry
+type entryValues struct {
+	Values map[string]interface{} `json:"values"`
+}
+
+//login issues a token for a form encoded username and password
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	user := r.PostForm.Get("username")
+	s.mu.Lock()
+	pass, ok := s.users[user]
+	var t string
+	if ok = ok && pass == r.PostForm.Get("password"); ok {
+		t = newToken()
+		s.tokens[t] = user
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, errAuth)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+	w.Write([]byte(t))
+}
+
+//logout revokes the request's token
+func (s *Server) logout(r *http.Request) *arError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, e := s.user(r); e != nil {
+		return e
+	}
+	delete(s.tokens, strings.TrimPrefix(r.Header.Get("Authorization"), "AR-JWT "))
+	return nil
+}
+
+//user returns the user for the request's AR-JWT token, the server
+//must be locked
+func (s *Server) user(r *http.Request) (string, *arError) {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "AR-JWT ") {
+		return "", errAuth
+	}
+	u, ok := s.tokens[strings.TrimPrefix(h, "AR-JWT ")]
+	if !ok {
+		return "", errAuth
+	}
+	return u, nil
+}
+
+//entry handles arsys/v1/entry/{form} and arsys/v1/entry/{form}/{id}
+//with the values of a POST or PUT already decoded, derr being the
+//error decoding them
+func (s *Server) entry(r *http.Request, derr error, in map[string]interface{}, parts []string) (response, *arError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, e := s.user(r)
+	if e != nil {
+		return response{}, e
+	}
+	name, err := url.PathUnescape(parts[0])
+	if err != nil || len(parts) > 2 {
+		return response{}, errorf(http.StatusNotFound, 0, r.URL.Path, "Resource not found")
+	}
+	f, ok := s.forms[name]
+	if !ok {
+		return response{}, errorf(http.StatusNotFound, 303, name, "Form does not exist on server")
+	}
+	self := s.URL + "/api/arsys/v1/entry/" + url.PathEscape(name)
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			return f.query(r, self)
+		case http.MethodPost:
+			if derr != nil {
+				return response{}, errorf(http.StatusBadRequest, 0, derr.Error(), "Unable to parse the request body")
+			}
+			id, e := f.create(user, in)
+			if e != nil {
+				return response{}, e
+			}
+			return response{status: http.StatusCreated, location: self + "/" + id}, nil
+		}
+		return response{}, errorf(http.StatusMethodNotAllowed, 0, r.Method, "Method not allowed")
+	}
+
+	id, _ := url.PathUnescape(parts[1])
+	values, ok := f.entries[id]
+	if !ok {
+		return response{}, errorf(http.StatusNotFound, 302, id, "Entry does not exist in database")
+	}
+	switch r.Method {
+	case http.MethodGet:
+		return response{status: http.StatusOK, body: entryJSON(self, id, values, fields(r))}, nil
+	case http.MethodPut:
+		if derr != nil {
+			return response{}, errorf(http.StatusBadRequest, 0, derr.Error(), "Unable to parse the request body")
+		}
+		for k, v := range in {
+			if k != "Request ID" && k != f.IDField {
+				values[k] = v
+			}
+		}
+		values["Last Modified By"] = user
+		values["Last Modified Date"] = timestamp()
+	case http.MethodDelete:
+		delete(f.entries, id)
+	default:
+		return response{}, errorf(http.StatusMethodNotAllowed, 0, r.Method, "Method not allowed")
+	}
+	return response{status: http.StatusNoContent}, nil
+}
+
+//create validates and stores a new entry, returning its id
+func (f *form) create(user string, in map[string]interface{}) (string, *arError) {
+	values := copyValues(f.Defaults)
+	for k, v := range in {
+		values[k] = v
+	}
+	for _, req := range f.Required {
+		if v, ok := values[req]; !ok || v == nil || v == "" {
+			return "", errorf(http.StatusBadRequest, 326, f.Name+" : "+req, "Required field cannot be blank.")
+		}
+	}
+	f.next++
+	id := fmt.Sprintf("%015d", f.next)
+	values["Request ID"] = id
+	if len(f.IDField) > 0 {
+		values[f.IDField] = fmt.Sprintf("%s%012d", f.IDPrefix, f.next)
+	}
+	now := timestamp()
+	values["Submitter"] = user
+	values["Submit Date"] = now
+	values["Last Modified By"] = user
+	values["Last Modified Date"] = now
+	f.entries[id] = values
+	return id, nil
+}
+
+//query returns the entries matching the q qualification, sorted
+//and paged by the sort, offset and limit parameters
+func (f *form) query(r *http.Request, self string) (response, *arError) {
+	params := r.URL.Query()
+	match, err := parseQualification(params.Get("q"))
+	if err != nil {
+		return response{}, errorf(http.StatusBadRequest, 1587, err.Error(), "The qualification line is invalid.")
+	}
+	offset, _ := strconv.Atoi(params.Get("offset"))
+	limit, _ := strconv.Atoi(params.Get("limit"))
+	if offset < 0 || limit < 0 {
+		return response{}, errorf(http.StatusBadRequest, 0, "offset, limit", "Invalid parameter value")
+	}
+
+	var ids []string
+	for id, v := range f.entries {
+		if match(v) {
+			ids = append(ids, id)
+		}
+	}
+	sortEntries(ids, f.entries, params.Get("sort"))
+
+	total := len(ids)
+	if offset > len(ids) {
+		offset = len(ids)
+	}
+	ids = ids[offset:]
+	if limit > 0 && limit < len(ids) {
+		ids = ids[:limit]
+	}
+
+	want := fields(r)
+	entries := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		entries = append(entries, entryJSON(self, id, f.entries[id], want))
+	}
+	links := map[string]interface{}{
+		"self": []interface{}{map[string]string{"href": self + "?" + r.URL.RawQuery}},
+	}
+	if limit > 0 && offset+limit < total {
+		params.Set("offset", strconv.Itoa(offset+limit))
+		links["next"] = []interface{}{map[string]string{"href": self + "?" + params.Encode()}}
+	}
+	return response{status: http.StatusOK, body: map[string]interface{}{
+		"entries": entries,
+		"_links":  links,
+	}}, nil
+}
+
+//sortEntries orders entry ids by the sort parameter, e.g.
+//"Urgency.asc,Submit Date.desc", then by id
+func sortEntries(ids []string, entries map[string]map[string]interface{}, by string) {
+	var keys []string
+	if len(by) > 0 {
+		keys = strings.Split(by, ",")
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := entries[ids[i]], entries[ids[j]]
+		for _, k := range keys {
+			field, desc := k, false
+			if n := strings.LastIndex(k, "."); n > 0 {
+				field, desc = k[:n], strings.EqualFold(k[n+1:], "desc")
+			}
+			if compare(a[field], b[field], "=") {
+				continue
+			}
+			return compare(a[field], b[field], "<") != desc
+		}
+		return ids[i] < ids[j]
+	})
+}
+
+//fields returns the field names requested by a
+//fields=values(a,b) parameter, nil for all fields
+func fields(r *http.Request) []string {
+	f := r.URL.Query().Get("fields")
+	if !strings.HasPrefix(f, "values(") || !strings.HasSuffix(f, ")") {
+		return nil
+	}
+	var out []string
+	for _, n := range strings.Split(f[len("values("):len(f)-1], ",") {
+		out = append(out, strings.TrimSpace(n))
+	}
+	return out
+}
+
+//entryJSON is the json representation of an entry with its link
+func entryJSON(self, id string, values map[string]interface{}, fields []string) map[string]interface{} {
+	v := copyValues(values)
+	if fields != nil {
+		v = make(map[string]interface{}, len(fields))
+		for _, f := range fields {
+			v[f] = values[f]
+		}
+	}
+	return map[string]interface{}{
+		"values": v,
+		"_links": map[string]interface{}{
+			"self": []interface{}{map[string]string{"href": self + "/" + id}},
+		},
+	}
+}
+
+func copyValues(v map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(v))
+	for k, x := range v {
+		c[k] = x
+	}
+	return c
+}
+
+//writeError writes a Remedy error array
+func writeError(w http.ResponseWriter, e *arError) {
+	writeJSON(w, e.status, []Message{e.msg})
+}
+
+//writeJSON writes v as the json response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+//timestamp returns the current time formatted as Remedy does
+func timestamp() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05.000+0000")
+}
+
+//newToken returns a random token
+func newToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
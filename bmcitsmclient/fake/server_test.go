@@ -0,0 +1,225 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+//do sends a request with the token and decodes a json response
+func do(t *testing.T, srv *Server, token, method, path string, body interface{}, out interface{}) *http.Response {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req, _ := http.NewRequest(method, srv.BaseURL()+path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "AR-JWT "+token)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp
+}
+
+func login(t *testing.T, srv *Server, user, pass string) (string, int) {
+	form := url.Values{"username": {user}, "password": {pass}}
+	resp, err := srv.Client().Post(srv.BaseURL()+"/jwt/login", "application/x-www-form-urlencoded",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var b bytes.Buffer
+	b.ReadFrom(resp.Body)
+	return b.String(), resp.StatusCode
+}
+
+func TestLoginLogout(t *testing.T) {
+	srv := NewServer("Demo", "P@ssw0rd")
+	defer srv.Close()
+
+	if _, code := login(t, srv, "Demo", "wrong"); code != 401 {
+		t.Fatalf("Expected 401, got %d", code)
+	}
+	token, code := login(t, srv, "Demo", "P@ssw0rd")
+	if code != 200 {
+		t.Fatalf("Login failed: %d", code)
+	}
+	var msgs []Message
+	resp := do(t, srv, token, "GET", "/arsys/v1/entry/HPD:NoSuchForm", nil, &msgs)
+	if resp.StatusCode != 404 || msgs[0].MessageNumber != 303 {
+		t.Fatalf("Expected missing form, got: %d %+v", resp.StatusCode, msgs)
+	}
+	if resp = do(t, srv, token, "POST", "/jwt/logout", nil, nil); resp.StatusCode != 204 {
+		t.Fatalf("Logout failed: %d", resp.StatusCode)
+	}
+	resp = do(t, srv, token, "GET", "/arsys/v1/entry/"+url.PathEscape(IncidentInterface), nil, &msgs)
+	if resp.StatusCode != 401 || msgs[0].MessageNumber != 623 {
+		t.Fatalf("Expected authentication failure, got: %d %+v", resp.StatusCode, msgs)
+	}
+}
+
+func TestEntryCRUD(t *testing.T) {
+	srv := NewServer("Demo", "P@ssw0rd")
+	defer srv.Close()
+	token, _ := login(t, srv, "Demo", "P@ssw0rd")
+	path := "/arsys/v1/entry/" + url.PathEscape(InfrastructureChange)
+
+	var msgs []Message
+	resp := do(t, srv, token, "POST", path, map[string]interface{}{"values": map[string]interface{}{}}, &msgs)
+	if resp.StatusCode != 400 || msgs[0].MessageNumber != 326 {
+		t.Fatalf("Expected required field error, got: %d %+v", resp.StatusCode, msgs)
+	}
+	resp = do(t, srv, token, "POST", path, map[string]interface{}{
+		"values": map[string]interface{}{"Description": "patch the routers"},
+	}, nil)
+	if resp.StatusCode != 201 {
+		t.Fatalf("Create failed: %d", resp.StatusCode)
+	}
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	entry := strings.TrimPrefix(loc.Path, "/api")
+
+	var e struct {
+		Values map[string]interface{} `json:"values"`
+	}
+	do(t, srv, token, "GET", entry, nil, &e)
+	if e.Values["Infrastructure Change ID"] != "CRQ000000000001" || e.Values["Change Request Status"] != "Draft" {
+		t.Fatalf("Unexpected entry: %v", e.Values)
+	}
+
+	resp = do(t, srv, token, "PUT", entry, map[string]interface{}{
+		"values": map[string]interface{}{"Change Request Status": "Scheduled"},
+	}, nil)
+	if resp.StatusCode != 204 {
+		t.Fatalf("Update failed: %d", resp.StatusCode)
+	}
+	v, _ := srv.Entry(InfrastructureChange, "000000000000001")
+	if v["Change Request Status"] != "Scheduled" {
+		t.Fatalf("Expected updated status, got: %v", v)
+	}
+
+	if resp = do(t, srv, token, "DELETE", entry, nil, nil); resp.StatusCode != 204 {
+		t.Fatalf("Delete failed: %d", resp.StatusCode)
+	}
+	resp = do(t, srv, token, "GET", entry, nil, &msgs)
+	if resp.StatusCode != 404 || msgs[0].MessageNumber != 302 {
+		t.Fatalf("Expected missing entry, got: %d %+v", resp.StatusCode, msgs)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	srv := NewServer("Demo", "P@ssw0rd")
+	defer srv.Close()
+	token, _ := login(t, srv, "Demo", "P@ssw0rd")
+
+	_, err := srv.Seed(IncidentInterface,
+		map[string]interface{}{"Description": "printer on fire", "Impact": "1-Extensive/Widespread", "Urgency": "1-Critical", "Priority Weight": 20},
+		map[string]interface{}{"Description": "mouse missing", "Impact": "4-Minor/Localized", "Urgency": "4-Low", "Priority Weight": 2},
+		map[string]interface{}{"Description": "fire drill", "Impact": "3-Moderate/Limited", "Urgency": "3-Medium", "Priority Weight": 8},
+		map[string]interface{}{"Description": "disk full", "Impact": "2-Significant/Large", "Urgency": "2-High", "Priority Weight": 14, "Status": "Resolved"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		Entries []struct {
+			Values map[string]interface{} `json:"values"`
+		} `json:"entries"`
+		Links map[string][]struct {
+			Href string `json:"href"`
+		} `json:"_links"`
+	}
+	query := func(params url.Values) result {
+		var r result
+		resp := do(t, srv, token, "GET", "/arsys/v1/entry/"+url.PathEscape(IncidentInterface)+"?"+params.Encode(), nil, &r)
+		if resp.StatusCode != 200 {
+			t.Fatalf("Query %v failed: %d", params, resp.StatusCode)
+		}
+		return r
+	}
+
+	tests := []struct {
+		q    string
+		want int
+	}{
+		{``, 4},
+		{`'Description' LIKE "%fire%"`, 2},
+		{`'Priority Weight' > 5 AND 'Status' = "New"`, 2},
+		{`'Urgency' = "4-Low" OR ('Status' != "New" AND NOT 'Priority Weight' < 10)`, 2},
+		{`'Assignee' = $NULL$`, 4},
+	}
+	for _, tt := range tests {
+		if r := query(url.Values{"q": {tt.q}}); len(r.Entries) != tt.want {
+			t.Fatalf("%s: expected %d entries, got %d", tt.q, tt.want, len(r.Entries))
+		}
+	}
+
+	r := query(url.Values{"sort": {"Priority Weight.desc"}, "offset": {"1"}, "limit": {"2"},
+		"fields": {"values(Incident Number)"}})
+	if len(r.Entries) != 2 || r.Entries[0].Values["Incident Number"] != "INC000000000004" || len(r.Entries[0].Values) != 1 {
+		t.Fatalf("Unexpected page: %+v", r.Entries)
+	}
+	if len(r.Links["next"]) != 1 || !strings.Contains(r.Links["next"][0].Href, "offset=3") {
+		t.Fatalf("Expected next link, got: %+v", r.Links)
+	}
+
+	var msgs []Message
+	resp := do(t, srv, token, "GET", "/arsys/v1/entry/"+url.PathEscape(IncidentInterface)+"?q="+url.QueryEscape(`'Status' = `), nil, &msgs)
+	if resp.StatusCode != 400 || msgs[0].MessageNumber != 1587 {
+		t.Fatalf("Expected invalid qualification, got: %d %+v", resp.StatusCode, msgs)
+	}
+}
+
+func TestSlowClient(t *testing.T) {
+	srv := NewServer("Demo", "P@ssw0rd")
+	defer srv.Close()
+	token, _ := login(t, srv, "Demo", "P@ssw0rd")
+
+	//a create whose body is still being sent
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	req, _ := http.NewRequest("POST", srv.BaseURL()+"/arsys/v1/entry/"+url.PathEscape(InfrastructureChange), pr)
+	req.Header.Set("Authorization", "AR-JWT "+token)
+	done := make(chan *http.Response, 1)
+	go func() {
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- resp
+	}()
+	pw.Write([]byte(`{"values": {"Description": "slow",`))
+
+	//other clients are served meanwhile
+	c := *srv.Client()
+	c.Timeout = 5 * time.Second
+	form := url.Values{"username": {"Demo"}, "password": {"P@ssw0rd"}}
+	resp, err := c.PostForm(srv.BaseURL()+"/jwt/login", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	pw.Write([]byte(` "Company": "Calbro Services"}}`))
+	pw.Close()
+	if resp = <-done; resp == nil {
+		t.Fatal("Expected the slow create to complete")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected the slow create to succeed, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,23 @@
+package bmcitsmclient
+
+/* All the structures used in marshal/unmarshal of json
+   to and from the Remedy service
+*/
+
+//NoMessage used of sending/receiving an empty json message
+type NoMessage struct{}
+
+//Entry struct for defining and marshal/unmarshal of a form entry.
+//Values are keyed by the form field name
+type Entry struct {
+	Values map[string]interface{} `json:"values"`
+}
+
+//ErrMessage is struct defining an error message returned by
+//the Remedy service. Errors are returned as an array of these
+type ErrMessage struct {
+	MessageType         string `json:"messageType"`
+	MessageText         string `json:"messageText"`
+	MessageAppendedText string `json:"messageAppendedText,omitempty"`
+	MessageNumber       int    `json:"messageNumber"`
+}
@@ -0,0 +1,109 @@
+package checkptclient
+
+import "context"
+
+const (
+	endpointAddAccessLayer      = `add-access-layer`
+	endpointShowAccessLayer     = `show-access-layer`
+	endpointShowAccessLayers    = `show-access-layers`
+	endpointSetAccessLayer      = `set-access-layer`
+	endpointDeleteAccessLayer   = `delete-access-layer`
+	endpointAddAccessRule       = `add-access-rule`
+	endpointShowAccessRule      = `show-access-rule`
+	endpointSetAccessRule       = `set-access-rule`
+	endpointDeleteAccessRule    = `delete-access-rule`
+	endpointShowAccessRulebase  = `show-access-rulebase`
+	endpointAddAccessSection    = `add-access-section`
+	endpointShowAccessSection   = `show-access-section`
+	endpointSetAccessSection    = `set-access-section`
+	endpointDeleteAccessSection = `delete-access-section`
+)
+
+//CreateAccessLayer creates an AccessLayer on the CheckPoint service
+func (a *APIClient) CreateAccessLayer(ctx context.Context, layer AccessLayer) (AccessLayer, error) {
+	return command[AccessLayer, AccessLayer](ctx, a, endpointAddAccessLayer, layer)
+}
+
+//ShowAccessLayer returns an AccessLayer by uid or name
+func (a *APIClient) ShowAccessLayer(ctx context.Context, id ObjectID) (AccessLayer, error) {
+	return command[showRequest, AccessLayer](ctx, a, endpointShowAccessLayer, showRequest{ObjectID: id})
+}
+
+//ShowAccessLayers returns a page of the access layers matching the
+//options
+func (a *APIClient) ShowAccessLayers(ctx context.Context, opts ListOptions) (AccessLayerList, error) {
+	return command[ListOptions, AccessLayerList](ctx, a, endpointShowAccessLayers, opts)
+}
+
+//SetAccessLayer changes the AccessLayer identified by its UID or
+//Name
+func (a *APIClient) SetAccessLayer(ctx context.Context, layer AccessLayer) (AccessLayer, error) {
+	return command[AccessLayer, AccessLayer](ctx, a, endpointSetAccessLayer, layer)
+}
+
+//DeleteAccessLayer deletes an AccessLayer by uid or name
+func (a *APIClient) DeleteAccessLayer(ctx context.Context, id ObjectID, opts DeleteOptions) error {
+	_, err := command[deleteRequest, NoMessage](ctx, a, endpointDeleteAccessLayer,
+		deleteRequest{ObjectID: id, DeleteOptions: opts})
+	return err
+}
+
+//CreateAccessRule adds a rule to the rule's Layer at the rule's
+//Position, which is required
+func (a *APIClient) CreateAccessRule(ctx context.Context, rule AccessRule) (AccessRule, error) {
+	return command[AccessRule, AccessRule](ctx, a, endpointAddAccessRule, rule)
+}
+
+//ShowAccessRule returns a rule of layer by uid or name
+func (a *APIClient) ShowAccessRule(ctx context.Context, layer string, id ObjectID) (AccessRule, error) {
+	return command[ruleRequest, AccessRule](ctx, a, endpointShowAccessRule, ruleRequest{ObjectID: id, Layer: layer})
+}
+
+//SetAccessRule changes the rule of the rule's Layer identified by
+//its UID, Name or RuleNumber. NewPosition moves the rule.
+func (a *APIClient) SetAccessRule(ctx context.Context, rule AccessRule) (AccessRule, error) {
+	return command[AccessRule, AccessRule](ctx, a, endpointSetAccessRule, rule)
+}
+
+//DeleteAccessRule deletes a rule of layer by uid or name
+func (a *APIClient) DeleteAccessRule(ctx context.Context, layer string, id ObjectID) error {
+	_, err := command[ruleRequest, NoMessage](ctx, a, endpointDeleteAccessRule, ruleRequest{ObjectID: id, Layer: layer})
+	return err
+}
+
+//ShowAccessRulebase returns a page of the rules of the access layer
+//id, with the sections containing them. The references of the rules
+//are resolved to objects.
+func (a *APIClient) ShowAccessRulebase(ctx context.Context, id ObjectID, opts RulebaseOptions) (AccessRulebase, error) {
+	rb, err := command[rulebaseRequest, AccessRulebase](ctx, a, endpointShowAccessRulebase,
+		rulebaseRequest{ObjectID: id, RulebaseOptions: opts, UseObjectDictionary: true})
+	if err != nil {
+		return rb, err
+	}
+	rb.dereference()
+	return rb, nil
+}
+
+//CreateAccessSection adds a section to the section's Layer at the
+//section's Position, which is required
+func (a *APIClient) CreateAccessSection(ctx context.Context, section AccessSection) (AccessSection, error) {
+	return command[AccessSection, AccessSection](ctx, a, endpointAddAccessSection, section)
+}
+
+//ShowAccessSection returns a section of layer by uid or name
+func (a *APIClient) ShowAccessSection(ctx context.Context, layer string, id ObjectID) (AccessSection, error) {
+	return command[ruleRequest, AccessSection](ctx, a, endpointShowAccessSection, ruleRequest{ObjectID: id, Layer: layer})
+}
+
+//SetAccessSection changes the section of the section's Layer
+//identified by its UID or Name
+func (a *APIClient) SetAccessSection(ctx context.Context, section AccessSection) (AccessSection, error) {
+	return command[AccessSection, AccessSection](ctx, a, endpointSetAccessSection, section)
+}
+
+//DeleteAccessSection deletes a section of layer by uid or name. The
+//rules of the section join the section above.
+func (a *APIClient) DeleteAccessSection(ctx context.Context, layer string, id ObjectID) error {
+	_, err := command[ruleRequest, NoMessage](ctx, a, endpointDeleteAccessSection, ruleRequest{ObjectID: id, Layer: layer})
+	return err
+}
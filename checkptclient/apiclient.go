@@ -1,24 +1,27 @@
 package checkptclient
 
 import (
-	"crypto/tls"
-	"crypto/x509"
-	"encoding/json"
-	"flag"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/ericroys/checkptclient/rest"
+	"github.com/ericroys/rest"
 )
 
 const (
-	endpointLogin   = `login`
-	endpointAddHost = `add-host`
-	endpointPublish = `publish`
+	endpointLogin       = `login`
+	endpointLoginAsRoot = `login-as-root`
+	endpointAddHost     = `add-host`
+	endpointShowHost    = `show-host`
+	endpointShowHosts   = `show-hosts`
+	endpointSetHost     = `set-host`
+	endpointDeleteHost  = `delete-host`
+	endpointPublish     = `publish`
+	endpointShowTask    = `show-task`
 )
 
 //APIConfig provides the construct for configuring the
@@ -27,7 +30,52 @@ type APIConfig struct {
 	//User, Pwd string
 	Baseurl  string
 	CertPath string
-	session  Session
+	//TLS configures trusted CAs, client certificates for mutual
+	//TLS, certificate pinning and other TLS settings
+	TLS rest.TLSOptions
+	//Transport, when set, sends the requests in place of the
+	//transport built from TLS, e.g. a rest.Recorder replaying
+	//recorded exchanges in tests
+	Transport http.RoundTripper
+	//Timeout is the default deadline applied to each operation
+	//when the caller's context does not already carry one
+	Timeout time.Duration
+	//Retry is the policy for resending failed requests, nil
+	//disables retries
+	Retry *rest.RetryPolicy
+	//Middleware is added to every request sent by the client,
+	//e.g. for logging, metrics or header injection
+	Middleware []rest.Middleware
+	//TaskInterval is how often WaitForTask checks on a task,
+	//once a second when zero
+	TaskInterval time.Duration
+	//TaskTimeout is how long WaitForTask waits for a task to
+	//complete, without limit when zero beyond the caller's
+	//context
+	TaskTimeout time.Duration
+	//WaitForPublish makes Publish wait for the publish task to
+	//complete and return an error if it did not succeed
+	WaitForPublish bool
+	//KeepAlive sends keepalive in the background while the client
+	//is logged in, every half of the session timeout, so that an
+	//idle session keeps its locks until Close
+	KeepAlive bool
+	//OnClose is what Close does with the changes pending in the
+	//session, they are kept by default
+	OnClose CloseAction
+	session Session
+	//asRoot logs in with login-as-root
+	asRoot bool
+}
+
+//retryMessages are fragments of Check Point error responses
+//reporting a transient condition, such as the session being
+//busy with another operation. They are narrow on purpose as
+//writes are retried on a match.
+var retryMessages = []string{
+	"server is busy",
+	"session is busy",
+	"too many requests",
 }
 
 //SetSessionLastPublish Allows login to an existing last
@@ -41,21 +89,69 @@ func (ac *APIConfig) SetSessionContLast(last bool) {
 	ac.session.SessCont = last
 }
 
+//SetReadOnly makes the client log in to read only sessions, which
+//may show but not change objects
+func (ac *APIConfig) SetReadOnly(readOnly bool) {
+	ac.session.ReadOnly = readOnly
+}
+
+//SetDomain sets the domain of a Multi-Domain Server to log in to,
+//by name or uid. The global domain is used when not set.
+func (ac *APIConfig) SetDomain(domain string) {
+	ac.session.Domain = domain
+}
+
+//SetMaxRetries sets the number of times a failed request is retried
+//using exponential backoff. Zero disables retries.
+func (ac *APIConfig) SetMaxRetries(retries int) {
+	if retries <= 0 {
+		ac.Retry = nil
+		return
+	}
+	p := rest.NewRetryPolicy(retries)
+	p.RetryBody = retryMessages
+	ac.Retry = p
+}
+
 //NewAPIConfig creates and initializes an APIConfig object.
-//Defaults to use the last session for the user
+//Defaults to use the last session for the user. Failed requests
+//are not retried unless enabled with SetMaxRetries or Retry.
 func NewAPIConfig(baseurl, user, pass, certpath string) *APIConfig {
-	s := Session{
-		User:        user,
-		Password:    pass,
-		SessTimeout: 600,
-		//default to continue last session
-		SessCont:    true,
-		SessContPub: false,
-	}
+	return newAPIConfig(baseurl, certpath, Session{User: user, Password: pass})
+}
+
+//NewAPIConfigAPIKey creates and initializes an APIConfig object
+//logging in with an api key, e.g. of a service account, in place
+//of a user and password. Defaults are those of NewAPIConfig.
+func NewAPIConfigAPIKey(baseurl, apiKey, certpath string) *APIConfig {
+	return newAPIConfig(baseurl, certpath, Session{APIKey: apiKey})
+}
+
+//NewAPIConfigRoot creates and initializes an APIConfig object
+//logging in as root with login-as-root, without credentials. This
+//is only allowed by the service for clients on the management
+//server itself, e.g. with a base url of https://127.0.0.1. Defaults
+//are those of NewAPIConfig.
+func NewAPIConfigRoot(baseurl, certpath string) *APIConfig {
+	ac := newAPIConfig(baseurl, certpath, Session{})
+	ac.asRoot = true
+	return ac
+}
+
+//newAPIConfig returns an APIConfig with the defaults for logging
+//in with the credentials of s
+func newAPIConfig(baseurl, certpath string, s Session) *APIConfig {
+	s.SessTimeout = 600
+	//default to continue last session
+	s.SessCont = true
+	s.SessContPub = false
 	ac := APIConfig{
-		Baseurl:  baseurl,
-		CertPath: certpath,
-		session:  s,
+		Baseurl:      baseurl,
+		CertPath:     certpath,
+		Timeout:      20 * time.Second,
+		TaskInterval: time.Second,
+		TaskTimeout:  5 * time.Minute,
+		session:      s,
 	}
 	return &ac
 }
@@ -63,103 +159,213 @@ func NewAPIConfig(baseurl, user, pass, certpath string) *APIConfig {
 //APIClient is the CheckPoint API Client. All interaction with
 //a Check Point service is done using methods provided by this
 //client.
+//
+//An APIClient is safe for concurrent use by multiple goroutines,
+//which share its session: the first request logs in, and requests
+//finding the session expired log in again once between them, so
+//that concurrent changes are made in the same session. Login,
+//Logout and the session switching methods change the session of
+//all goroutines, and Publish and Discard act on the changes of all
+//of them. The APIConfig must not be changed once the client is
+//created.
 type APIClient struct {
-	conf        *APIConfig
-	httpClient  *http.Client
-	sid         string
-	nextRefresh time.Time
+	conf       *APIConfig
+	httpClient *http.Client
+	//auth holds the X-chkp-sid session, logging in on first
+	//use and again when the session expires
+	auth *rest.AuthSession
+	//global, when set, is the client of the global domain the
+	//client logs in through with login-to-domain
+	global *APIClient
+	//sessTimeout is the session timeout in seconds given by the
+	//last login, accessed atomically
+	sessTimeout int64
+	//mu guards stop, which ends the keepalive loop while it runs
+	mu   sync.Mutex
+	stop chan struct{}
 }
 
-func (a *APIClient) getSID() error {
-	n := time.Now()
-	if a.sid == "" || n.After(a.nextRefresh) {
-		err := a.Login()
-		if err != nil {
-			return err
-		}
-	}
-	if a.sid == "" {
-		return fmt.Errorf("unable to obtain the session identifier")
+//sessionExpired are fragments of Check Point error responses
+//for a session id that is no longer valid
+var sessionExpired = []string{
+	"generic_err_wrong_session_id",
+	"session may be expired",
+}
+
+//Login logs into the Check Point service and
+//stores the session identifier with the client.
+//Calling Login is optional, the client logs in
+//as needed.
+func (a *APIClient) Login(ctx context.Context) error {
+	if err := a.auth.Refresh(ctx); err != nil {
+		return err
 	}
+	a.loggedIn()
 	return nil
 }
 
-//Login logs into the Check Point service and
-//returns a session identifier and session timeout
-//to the client
-func (a *APIClient) Login() error {
-	var resp LoginResponse
+//login logs into the Check Point service and returns
+//the session identifier and how long it is valid for
+func (a *APIClient) login(ctx context.Context) (string, time.Duration, error) {
 	//l := a.conf.session
-	uri, err := a.getPath(endpointLogin, "")
+	endpoint := endpointLogin
+	switch {
+	case a.global != nil:
+		endpoint = endpointLoginToDomain
+	case a.conf.asRoot:
+		endpoint = endpointLoginAsRoot
+	}
+	uri, err := a.getPath(endpoint, "")
 	if err != nil {
-		return err
+		return "", 0, err
+	}
+	var resp LoginResponse
+	if a.global != nil {
+		//log in through the session of the global domain
+		resp, err = send[loginToDomainRequest, LoginResponse](ctx, a.global, uri, loginToDomainRequest{
+			Domain:   a.conf.session.Domain,
+			SessCont: a.conf.session.SessCont,
+		}, true, rest.RequireFields("sid"))
+	} else {
+		resp, err = send[Session, LoginResponse](ctx, a, uri, a.conf.session, false,
+			rest.RequireFields("sid"))
 	}
-	err = a.send(uri, &a.conf.session, &resp, false)
 	if err != nil {
-		return err
+		return "", 0, err
 	}
 
-	log.Printf("Sid: %s\nTimeout: %d\n", resp.Sid, resp.SessTimeout)
-	a.sid = resp.Sid
+	atomic.StoreInt64(&a.sessTimeout, int64(resp.SessTimeout))
 
 	//pad in a 5 second buffer for the timeout
-	a.nextRefresh = time.Now().Add(
-		time.Duration(resp.SessTimeout-5) * time.Second)
-	return nil
+	return resp.Sid, time.Duration(resp.SessTimeout-5) * time.Second, nil
 }
 
 //CreateHost creates a Host on the CheckPoint service
-func (a *APIClient) CreateHost(host Host) (Host, error) {
+func (a *APIClient) CreateHost(ctx context.Context, host Host) (Host, error) {
 	var h Host
 	uri, err := a.getPath(endpointAddHost, "")
 	if err != nil {
 		return h, err
 	}
 
-	err = a.send(uri, &host, &h, true)
-	if err != nil {
-		return h, err
-	}
-	return h, nil
+	return send[Host, Host](ctx, a, uri, host, true)
 }
 
-func (a *APIClient) Publish() error {
+//ShowHost returns a Host by uid or name. IsNotFound reports
+//whether the error is for a host that does not exist.
+func (a *APIClient) ShowHost(ctx context.Context, id ObjectID) (Host, error) {
+	return command[showRequest, Host](ctx, a, endpointShowHost, showRequest{ObjectID: id})
+}
 
-	var msg NoMessage
-	uri, err := a.getPath(endpointPublish, "")
-	if err != nil {
+//ShowHosts returns a page of the hosts matching the options
+func (a *APIClient) ShowHosts(ctx context.Context, opts ListOptions) (ObjectList[Host], error) {
+	return command[ListOptions, ObjectList[Host]](ctx, a, endpointShowHosts, opts)
+}
+
+//SetHost changes the Host identified by its UID or Name. Newname
+//renames the host, other fields are changed when set.
+func (a *APIClient) SetHost(ctx context.Context, host Host) (Host, error) {
+	return command[Host, Host](ctx, a, endpointSetHost, host)
+}
+
+//DeleteHost deletes a Host by uid or name
+func (a *APIClient) DeleteHost(ctx context.Context, id ObjectID, opts DeleteOptions) error {
+	_, err := command[deleteRequest, NoMessage](ctx, a, endpointDeleteHost,
+		deleteRequest{ObjectID: id, DeleteOptions: opts})
+	return err
+}
+
+//Publish publishes the changes made in the current session. The
+//publish completes in the background unless the config's
+//WaitForPublish is set, see PublishTask to wait for it selectively.
+func (a *APIClient) Publish(ctx context.Context) error {
+	id, err := a.PublishTask(ctx)
+	if err != nil || !a.conf.WaitForPublish {
 		return err
 	}
+	_, err = a.WaitForTask(ctx, id)
+	return err
+}
 
-	err = a.send(uri, &msg, &msg, true)
+//PublishTask starts publishing the changes made in the current
+//session and returns the id of the publish task, see WaitForTask
+func (a *APIClient) PublishTask(ctx context.Context) (string, error) {
+	uri, err := a.getPath(endpointPublish, "")
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	resp, err := send[NoMessage, taskResponse](ctx, a, uri, NoMessage{}, true)
+	return resp.TaskID, err
+}
+
+//ShowTasks returns the tasks with ids, with full details
+func (a *APIClient) ShowTasks(ctx context.Context, ids ...string) ([]Task, error) {
+	resp, err := command[showTaskRequest, struct {
+		Tasks []Task `json:"tasks"`
+	}](ctx, a, endpointShowTask, showTaskRequest{TaskID: ids, DetailsLevel: DetailsFull})
+	return resp.Tasks, err
+}
+
+//WaitForTask checks on the task with taskID every TaskInterval
+//until it completes, up to TaskTimeout or the end of ctx. The task
+//is returned with a *TaskError if it failed or partially succeeded,
+//or with the context's error if it did not complete in time.
+func (a *APIClient) WaitForTask(ctx context.Context, taskID string) (Task, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if a.conf.TaskTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.conf.TaskTimeout)
+		defer cancel()
+	}
+	interval := a.conf.TaskInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	var task Task
+	for {
+		tasks, err := a.ShowTasks(ctx, taskID)
+		if err != nil {
+			return task, err
+		}
+		if len(tasks) != 1 {
+			return task, fmt.Errorf("show-task returned %d tasks for [%s]", len(tasks), taskID)
+		}
+		task = tasks[0]
+		if task.Done() {
+			return task, task.Err()
+		}
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return task, fmt.Errorf("waiting for task %s [%s]: %w", task.TaskName, taskID, ctx.Err())
+		case <-t.C:
+		}
 	}
-	return nil
 }
 
-func (a *APIClient) getSender(uri string, msg []byte, auth bool) (*rest.Request, error) {
+//getBuilder returns a RequestableBuilder for a Check Point command,
+//adding the session id when auth is set
+func (a *APIClient) getBuilder(ctx context.Context, uri string, auth bool) (*rest.RequestableBuilder, error) {
 
 	builder := rest.NewRequestBuilder(uri, a.httpClient).
+		Context(ctx).
+		Retry(a.conf.Retry).
+		Use(a.conf.Middleware...).
 		Auth(rest.AuthNoAuth{}).
-		Header("Accept", "application/json").
-		Message(msg).
 		Method(rest.POST).
 		ErrorHandler(ErrHandler{})
 
-	//if auth flag then need to get the session id to pass in the
-	//header.
+	//if auth flag then the session id is passed in the
+	//header, logging in if needed
 	if auth {
-		//make sure we have a current sid
-		if err := a.getSID(); err != nil {
-			return nil, err
-		}
-		//set the header with current sid
-		builder.Header("X-chkp-sid", a.sid)
+		builder.Auth(a.auth)
 	}
-
-	v, err := builder.Build()
-	return v, err
+	return builder, nil
 }
 
 //NewClient initializes and validates a new APIClient provided
@@ -170,50 +376,37 @@ func NewClient(conf *APIConfig) (*APIClient, error) {
 		return nil, err
 	}
 
-	//customize client so set timeout and connection pool stuff
-	trans := &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 100,
-	}
-
-	//get certificate stuff
-	if len(conf.CertPath) > 0 {
-		p, err := buildCertPool(conf.CertPath)
+	//customize client so set tls and connection pool stuff unless
+	//a transport is supplied, CertPath is trusted in addition to
+	//any TLS.CAFiles
+	trans := conf.Transport
+	if trans == nil {
+		opts := conf.TLS
+		if len(conf.CertPath) > 0 {
+			opts.CAFiles = append([]string{conf.CertPath}, opts.CAFiles...)
+		}
+		t, err := opts.Transport()
 		if err != nil {
 			return nil, err
 		}
-		trans.TLSClientConfig = p
-	}
-	return &APIClient{
-		conf: conf,
-		httpClient: &http.Client{
-			Transport: trans,
-			Timeout:   20 * time.Second,
-		},
-	}, nil
-}
-
-//buildCertPool
-func buildCertPool(crtpath string) (*tls.Config, error) {
-	cert, err := ioutil.ReadFile(crtpath)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to read certificate file %s", crtpath)
+		trans = t
 	}
 
-	//for self signed certs
-	insecure := flag.Bool("insecure-ssl", false, "Accept/Ignore all server SSL certificates")
-	flag.Parse()
+	//no client wide timeout, deadlines are applied per operation
+	//via context (see APIConfig.Timeout)
+	return newClient(conf, &http.Client{Transport: trans}, nil), nil
+}
 
-	certPool, _ := x509.SystemCertPool()
-	if certPool == nil {
-		certPool = x509.NewCertPool()
+//newClient returns an APIClient for conf sending requests with
+//httpClient, logging in through global when set
+func newClient(conf *APIConfig, httpClient *http.Client, global *APIClient) *APIClient {
+	a := &APIClient{
+		conf:       conf,
+		httpClient: httpClient,
+		global:     global,
 	}
-	certPool.AppendCertsFromPEM(cert)
-
-	return &tls.Config{
-		InsecureSkipVerify: *insecure,
-		RootCAs:            certPool,
-	}, nil
+	a.auth = rest.NewAuthSession(sidHeader, "", a.login, sessionExpired...)
+	return a
 }
 
 //checks if url is valid, errors of not
@@ -250,49 +443,44 @@ func (a *APIClient) getPath(uri string, id string) (string, error) {
 	return rurl, nil
 }
 
-//getMessage accepts an interface to convert
-//to []byte to be used as message sent to server
-func getMessage(i interface{}) ([]byte, error) {
-
-	t, err := json.Marshal(&i)
-	if err != nil {
-		return nil, fmt.Errorf("Unable to create json message. %s", err)
+//withTimeout applies the configured default timeout to ctx unless
+//the caller already set a deadline
+func (a *APIClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	if t == nil || len(t) < 1 {
-		return nil, fmt.Errorf("Unable to create json message. No message content")
+	if _, ok := ctx.Deadline(); ok || a.conf.Timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
-	return t, nil
+	return context.WithTimeout(ctx, a.conf.Timeout)
 }
 
-func getResponse(data []byte, i interface{}) error {
-	err := json.Unmarshal(data, &i)
+//command sends msg to a Check Point command in the client's
+//session and transforms the response into a Resp
+func command[Req, Resp any](ctx context.Context, a *APIClient, cmd string, msg Req) (Resp, error) {
+	var out Resp
+	uri, err := a.getPath(cmd, "")
 	if err != nil {
-		return fmt.Errorf("failed to transform response message. %v", err)
+		return out, err
 	}
-	return nil
+	return send[Req, Resp](ctx, a, uri, msg, true)
 }
 
-func (a *APIClient) send(url string, msg interface{}, resp interface{}, auth bool) error {
+//send posts msg to the Check Point command at url and
+//transforms the response into a Resp
+func send[Req, Resp any](ctx context.Context, a *APIClient, url string, msg Req, auth bool, opts ...rest.JSONOption) (Resp, error) {
 
-	//build message
-	toMsg, err := getMessage(&msg)
-	fmt.Println(string(toMsg))
-	if err != nil {
-		return err
-	}
-	s, err := a.getSender(url, toMsg, auth)
-	if err != nil {
-		return err
-	}
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
 
-	data, err := s.Send()
+	var out Resp
+	b, err := a.getBuilder(ctx, url, auth)
 	if err != nil {
-		return err
+		return out, err
 	}
-	//log.Printf("data before response trans: %s", string(data))
-	err = getResponse(data, &resp)
-	if err != nil {
-		return err
+	out, _, err = rest.DoJSON[Req, Resp](ctx, b, msg, opts...)
+	if auth && err == nil {
+		a.loggedIn()
 	}
-	return nil
+	return out, err
 }
@@ -1,31 +1,90 @@
 package checkptclient
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
 
-func client() (*APIClient, error) {
+	"github.com/ericroys/checkptclient/fake"
+	"github.com/ericroys/rest"
+)
+
+//liveURL is the base url of the management server the cassettes
+//in testdata are recorded from
+const liveURL = "https://r8020sms.seamlessti.net/web_api/v1.3"
+
+func client(t *testing.T) (*APIClient, error) {
 
 	conf := NewAPIConfig(
-		"https://r8020sms.seamlessti.net/web_api/v1.3",
+		liveURL,
 		"admin",
 		"vpn12345",
 		`c:/test/certs/chkpnt.pem`,
 	)
+	conf.Transport = cassette(t, conf)
 	return NewClient(conf)
 }
 
+//cassette returns a transport replaying the test's cassette from
+//testdata, or recording it from the live service when REST_RECORD
+//is set in the environment. The test is skipped when there is no
+//cassette to replay.
+func cassette(t *testing.T, conf *APIConfig) http.RoundTripper {
+	var (
+		mode = rest.ModeReplay
+		real http.RoundTripper
+	)
+	path := filepath.Join("testdata", t.Name()+".json")
+	if len(os.Getenv("REST_RECORD")) > 0 {
+		mode = rest.ModeRecord
+		opts := conf.TLS
+		opts.CAFiles = append([]string{conf.CertPath}, opts.CAFiles...)
+		trans, err := opts.Transport()
+		if err != nil {
+			t.Fatal(err)
+		}
+		real = trans
+	} else if _, err := os.Stat(path); err != nil {
+		t.Skipf("No cassette %s, set REST_RECORD to record it from %s", path, conf.Baseurl)
+	}
+	rec, err := rest.NewRecorder(path, mode, real)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := rec.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+	return rec
+}
+
+//TestConfig creates a client for the live service, which does not
+//connect until the first request
 func TestConfig(t *testing.T) {
-	if _, err := client(); err != nil {
+	if _, err := NewClient(NewAPIConfig(liveURL, "admin", "vpn12345", "")); err != nil {
 		t.Fatal(err)
 	}
 }
 func TestLogin(t *testing.T) {
-	c, err := client()
+	c, err := client(t)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	//no extra params
-	err = c.Login()
+	err = c.Login(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -33,7 +92,7 @@ func TestLogin(t *testing.T) {
 }
 
 func TestCreateHost(t *testing.T) {
-	c, err := client()
+	c, err := client(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -44,7 +103,7 @@ func TestCreateHost(t *testing.T) {
 		Ipv4address: "192.168.2.145",
 	}
 	//no extra params
-	r, err := c.CreateHost(h)
+	r, err := c.CreateHost(context.Background(), h)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -52,14 +111,1024 @@ func TestCreateHost(t *testing.T) {
 }
 
 func TestPublish(t *testing.T) {
-	c, err := client()
+	c, err := client(t)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	//no extra params
-	err = c.Publish()
+	err = c.Publish(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+//fakeClient returns a client for a fake management server
+func fakeClient(t *testing.T) (*APIClient, *fake.Server) {
+	srv := fake.NewServer("admin", "vpn12345")
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(NewAPIConfig(srv.BaseURL(), "admin", "vpn12345", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, srv
+}
+
+func TestFakeCreateHostPublish(t *testing.T) {
+	c, srv := fakeClient(t)
+	ctx := context.Background()
+
+	h, err := c.CreateHost(ctx, Host{Name: "web1", Ipv4address: "10.1.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.UID) == 0 || h.Ipv4address != "10.1.1.1" {
+		t.Fatalf("Unexpected host: %+v", h)
+	}
+	if _, ok := srv.Published("host", "web1"); ok {
+		t.Fatal("Expected host to be pending until published")
+	}
+
+	//an expired session is logged in again
+	srv.ExpireSessions()
+	if err = c.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.Published("host", "web1"); !ok {
+		t.Fatal("Expected host to be published")
+	}
+
+	_, err = c.CreateHost(ctx, Host{Name: "web1", Ipv4address: "10.1.1.2"})
+	if rest.StatusCode(err) != 400 {
+		t.Fatalf("Expected duplicate name error, got: %v", err)
+	}
+	if he, ok := err.(*rest.HTTPError); !ok || he.Code != "err_validation_failed" {
+		t.Fatalf("Expected validation error, got: %v", err)
+	}
+}
+
+func TestFakeHostLifecycle(t *testing.T) {
+	c, _ := fakeClient(t)
+	ctx := context.Background()
+
+	for i, ip := range []string{"10.1.1.1", "10.1.1.2", "10.2.1.1"} {
+		_, err := c.CreateHost(ctx, Host{Name: fmt.Sprintf("web%d", i+1), Ipv4address: ip})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := c.ShowHost(ctx, ByName("web1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, err = c.ShowHost(ctx, ByUID(h.UID)); err != nil || h.Name != "web1" {
+		t.Fatalf("Unexpected host by uid: %+v %v", h, err)
+	}
+
+	h, err = c.SetHost(ctx, Host{
+		UID:         h.UID,
+		Newname:     "web1-renamed",
+		Ipv4address: "10.1.1.11",
+		Color:       "red",
+		NatSettings: NatSettings{Autorule: true, Method: "hide", Hidebehind: "gateway"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Name != "web1-renamed" || h.Ipv4address != "10.1.1.11" || h.Color != "red" || !h.Autorule {
+		t.Fatalf("Unexpected host after set: %+v", h)
+	}
+	//a rename leaves the NAT settings alone
+	h, err = c.SetHost(ctx, Host{Name: "web1-renamed", Newname: "web1"})
+	if err != nil || h.Method != "hide" {
+		t.Fatalf("Unexpected host after rename: %+v %v", h, err)
+	}
+
+	l, err := c.ShowHosts(ctx, ListOptions{Filter: "10.1.", Limit: 1, DetailsLevel: DetailsFull})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Total != 2 || len(l.Objects) != 1 || l.Objects[0].Name != "web1" || !l.More() {
+		t.Fatalf("Unexpected first page: %+v", l)
+	}
+	if l, err = c.ShowHosts(ctx, ListOptions{Filter: "10.1.", Offset: 1}); err != nil || l.From != 2 || l.More() {
+		t.Fatalf("Unexpected last page: %+v %v", l, err)
+	}
+
+	if err = c.DeleteHost(ctx, ByName("web2"), DeleteOptions{IgnoreWarnings: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ShowHost(ctx, ByName("web2")); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+	if err = c.DeleteHost(ctx, ByName("web2"), DeleteOptions{}); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+}
+
+func TestFakeNetworkGroup(t *testing.T) {
+	c, _ := fakeClient(t)
+	ctx := context.Background()
+
+	n, err := c.CreateNetwork(ctx, Network{Name: "net-web", Subnet4: "10.1.0.0", MaskLength4: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Broadcast != "allow" {
+		t.Fatalf("Unexpected network: %+v", n)
+	}
+	if _, err = c.CreateNetwork(ctx, Network{Name: "net-bad", Subnet4: "10.2.0.0", MaskLength4: 33}); err == nil {
+		t.Fatal("Expected invalid mask length to fail")
+	}
+	if n, err = c.SetNetwork(ctx, Network{UID: n.UID, MaskLength4: 24, Subnet4: "10.1.1.0"}); err != nil || n.MaskLength4 != 24 {
+		t.Fatalf("Unexpected network after set: %+v %v", n, err)
+	}
+
+	r, err := c.CreateAddressRange(ctx, AddressRange{Name: "range-db",
+		Ipv4addressFirst: "10.3.0.10", Ipv4addressLast: "10.3.0.20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.CreateAddressRange(ctx, AddressRange{Name: "range-bad",
+		Ipv4addressFirst: "10.3.0.20", Ipv4addressLast: "10.3.0.10"}); err == nil {
+		t.Fatal("Expected reversed range to fail")
+	}
+	h, err := c.CreateHost(ctx, Host{Name: "web1", Ipv4address: "10.1.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := c.CreateGroup(ctx, Group{Name: "servers", Members: []Member{{Name: "net-web"}, {UID: r.UID}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Members) != 2 || g.Members[0].UID != n.UID || g.Members[0].Type != "network" {
+		t.Fatalf("Unexpected group: %+v", g)
+	}
+	if g, err = c.AddGroupMembers(ctx, ByName("servers"), Member{UID: h.UID}); err != nil || len(g.Members) != 3 {
+		t.Fatalf("Unexpected group after add: %+v %v", g, err)
+	}
+	if g, err = c.RemoveGroupMembers(ctx, ByUID(g.UID), Member{Name: "net-web"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Members) != 2 || g.Members[0].Name != "range-db" || g.Members[1].Name != "web1" {
+		t.Fatalf("Unexpected group after remove: %+v", g)
+	}
+	if _, err = c.AddGroupMembers(ctx, ByName("servers"), Member{Name: "nope"}); err == nil {
+		t.Fatal("Expected unknown member to fail")
+	}
+	if g, err = c.SetGroup(ctx, Group{Name: "servers", Members: []Member{{Name: "net-web"}}}); err != nil || len(g.Members) != 1 {
+		t.Fatalf("Unexpected group after set: %+v %v", g, err)
+	}
+
+	l, err := c.ShowGroups(ctx, ListOptions{})
+	if err != nil || l.Total != 1 || l.Objects[0].Members[0].Name != "net-web" {
+		t.Fatalf("Unexpected groups: %+v %v", l, err)
+	}
+	if nl, err := c.ShowNetworks(ctx, ListOptions{Filter: "10.1."}); err != nil || nl.Total != 1 {
+		t.Fatalf("Unexpected networks: %+v %v", nl, err)
+	}
+	if rl, err := c.ShowAddressRanges(ctx, ListOptions{}); err != nil || rl.Total != 1 {
+		t.Fatalf("Unexpected address ranges: %+v %v", rl, err)
+	}
+
+	if err = c.DeleteGroup(ctx, ByName("servers"), DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ShowGroup(ctx, ByName("servers")); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+	if err = c.DeleteNetwork(ctx, ByUID(n.UID), DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.DeleteAddressRange(ctx, ByName("range-db"), DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ShowAddressRange(ctx, ByName("range-db")); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+	if _, err = c.ShowNetwork(ctx, ByName("net-web")); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+}
+
+func TestFakeServices(t *testing.T) {
+	c, _ := fakeClient(t)
+	ctx := context.Background()
+
+	tcp, err := c.CreateServiceTCP(ctx, ServiceTCP{Name: "tcp-app", Port: "8080-8090",
+		SessionTimeout: 600, UseDefaultSessionTimeout: Bool(false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tcp.SessionTimeout != 600 || *tcp.UseDefaultSessionTimeout || !*tcp.MatchForAny {
+		t.Fatalf("Unexpected tcp service: %+v", tcp)
+	}
+	for _, port := range []string{"", "90-80", "70000", "http"} {
+		if _, err = c.CreateServiceTCP(ctx, ServiceTCP{Name: "tcp-bad", Port: port}); err == nil {
+			t.Fatalf("Expected port %q to fail", port)
+		}
+	}
+	if tcp, err = c.SetServiceTCP(ctx, ServiceTCP{Name: "tcp-app", Port: ">1024", Protocol: "HTTP"}); err != nil ||
+		tcp.Port != ">1024" || tcp.Protocol != "HTTP" {
+		t.Fatalf("Unexpected tcp service after set: %+v %v", tcp, err)
+	}
+	udp, err := c.CreateServiceUDP(ctx, ServiceUDP{Name: "udp-syslog", Port: "514"})
+	if err != nil || udp.SessionTimeout != 40 || udp.AcceptReplies == nil {
+		t.Fatalf("Unexpected udp service: %+v %v", udp, err)
+	}
+	icmp, err := c.CreateServiceICMP(ctx, ServiceICMP{Name: "icmp-echo-reply", IcmpType: Int(0)})
+	if err != nil || icmp.IcmpType == nil || *icmp.IcmpType != 0 || *icmp.IcmpCode != 0 {
+		t.Fatalf("Unexpected icmp service: %+v %v", icmp, err)
+	}
+	if _, err = c.CreateServiceICMP(ctx, ServiceICMP{Name: "icmp-bad"}); err == nil {
+		t.Fatal("Expected missing icmp type to fail")
+	}
+
+	g, err := c.CreateServiceGroup(ctx, ServiceGroup{Name: "app-services",
+		Members: []Member{{Name: "tcp-app"}, {UID: udp.UID}}})
+	if err != nil || len(g.Members) != 2 || g.Members[0].Type != "service-tcp" {
+		t.Fatalf("Unexpected service group: %+v %v", g, err)
+	}
+	if g, err = c.AddServiceGroupMembers(ctx, ByName("app-services"), Member{Name: "icmp-echo-reply"}); err != nil ||
+		len(g.Members) != 3 {
+		t.Fatalf("Unexpected service group after add: %+v %v", g, err)
+	}
+	if g, err = c.RemoveServiceGroupMembers(ctx, ByUID(g.UID), Member{UID: udp.UID}); err != nil || len(g.Members) != 2 {
+		t.Fatalf("Unexpected service group after remove: %+v %v", g, err)
+	}
+	if _, err = c.CreateHost(ctx, Host{Name: "web1", Ipv4address: "10.1.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.AddServiceGroupMembers(ctx, ByName("app-services"), Member{Name: "web1"}); err == nil {
+		t.Fatal("Expected a host member of a service group to fail")
+	}
+
+	for name, typ := range map[string]string{"tcp-app": "service-tcp", "udp-syslog": "service-udp",
+		"icmp-echo-reply": "service-icmp", "app-services": "service-group"} {
+		m, err := c.LookupService(ctx, name)
+		if err != nil || m.Name != name || m.Type != typ || len(m.UID) == 0 {
+			t.Fatalf("Unexpected lookup of %s: %+v %v", name, m, err)
+		}
+	}
+	if _, err = c.LookupService(ctx, "web1"); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+
+	l, err := c.ShowServicesTCP(ctx, ListOptions{Limit: 1})
+	if err != nil || l.Total != 1 || l.Objects[0].Name != "tcp-app" {
+		t.Fatalf("Unexpected tcp services: %+v %v", l, err)
+	}
+	if ul, err := c.ShowServicesUDP(ctx, ListOptions{}); err != nil || ul.Total != 1 {
+		t.Fatalf("Unexpected udp services: %+v %v", ul, err)
+	}
+	if il, err := c.ShowServicesICMP(ctx, ListOptions{}); err != nil || il.Total != 1 {
+		t.Fatalf("Unexpected icmp services: %+v %v", il, err)
+	}
+	if gl, err := c.ShowServiceGroups(ctx, ListOptions{}); err != nil || gl.Total != 1 || len(gl.Objects[0].Members) != 2 {
+		t.Fatalf("Unexpected service groups: %+v %v", gl, err)
+	}
+
+	if err = c.DeleteServiceGroup(ctx, ByName("app-services"), DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.DeleteServiceTCP(ctx, ByUID(tcp.UID), DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.DeleteServiceUDP(ctx, ByName("udp-syslog"), DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.DeleteServiceICMP(ctx, ByName("icmp-echo-reply"), DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ShowServiceICMP(ctx, ByName("icmp-echo-reply")); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+	if _, err = c.ShowServiceGroup(ctx, ByName("app-services")); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+}
+
+func TestFakeAccessRulebase(t *testing.T) {
+	c, _ := fakeClient(t)
+	ctx := context.Background()
+
+	for i, ip := range []string{"10.1.1.1", "10.1.1.2"} {
+		if _, err := c.CreateHost(ctx, Host{Name: fmt.Sprintf("web%d", i+1), Ipv4address: ip}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.CreateGroup(ctx, Group{Name: "servers", Members: []Member{{Name: "web2"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateServiceTCP(ctx, ServiceTCP{Name: "tcp-443", Port: "443"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rb, err := c.ShowAccessRulebase(ctx, ByName("Network"), RulebaseOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
+	rules := rb.Rules()
+	if rb.Total != 1 || len(rules) != 1 || rules[0].Name != "Cleanup rule" || rules[0].Action.Name != "Drop" ||
+		rules[0].Source[0].Name != "Any" {
+		t.Fatalf("Unexpected initial rulebase: %+v", rb)
+	}
+
+	if _, err = c.CreateAccessSection(ctx, AccessSection{Layer: "Network", Name: "Web", Position: PositionTop()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.CreateAccessSection(ctx, AccessSection{Layer: "Network", Name: "Default",
+		Position: PositionAbove("Cleanup rule")}); err != nil {
+		t.Fatal(err)
+	}
+	web, err := c.CreateAccessRule(ctx, AccessRule{
+		Layer:       "Network",
+		Name:        "allow web",
+		Position:    PositionTopOf("Web"),
+		Action:      &Member{Name: "Accept"},
+		Source:      []Member{{Name: "web1"}},
+		Destination: []Member{{Name: "servers"}},
+		Service:     []Member{{Name: "tcp-443"}},
+		Track:       &Track{Type: &Member{Name: "Log"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if web.Action.Name != "Accept" || web.Destination[0].Type != "group" || web.Time[0].Name != "Any" {
+		t.Fatalf("Unexpected rule: %+v", web)
+	}
+	for _, r := range []AccessRule{
+		{Layer: "Network", Name: "allow ssh", Position: PositionBelow("allow web"), Action: &Member{Name: "Accept"}},
+		{Layer: "Network", Name: "first", Position: PositionAbove(web.UID)},
+	} {
+		if _, err = c.CreateAccessRule(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = c.CreateAccessRule(ctx, AccessRule{Layer: "Network", Position: PositionTop(),
+		Action: &Member{Name: "web1"}}); err == nil {
+		t.Fatal("Expected a host action to fail")
+	}
+
+	rb, err = c.ShowAccessRulebase(ctx, ByName("Network"), RulebaseOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rb.Total != 4 || !rb.More() || len(rb.Rulebase) != 1 || rb.Rulebase[0].Section == nil {
+		t.Fatalf("Unexpected first page: %+v", rb)
+	}
+	rules = rb.Rules()
+	if rules[0].Name != "first" || rules[1].Name != "allow web" || rules[1].Source[0].Name != "web1" ||
+		rules[1].Track.Type.Name != "Log" || rules[1].RuleNumber != 2 {
+		t.Fatalf("Unexpected first page rules: %+v", rules)
+	}
+	rb, err = c.ShowAccessRulebase(ctx, ByName("Network"), RulebaseOptions{Offset: 2, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rb.From != 3 || rb.More() || len(rb.Rulebase) != 2 || rb.Rulebase[1].Section.Name != "Default" ||
+		rb.Rulebase[1].Section.Rulebase[0].Name != "Cleanup rule" {
+		t.Fatalf("Unexpected last page: %+v", rb)
+	}
+	if rb, err = c.ShowAccessRulebase(ctx, ByName("Network"), RulebaseOptions{Filter: "web1"}); err != nil || rb.Total != 1 {
+		t.Fatalf("Unexpected filtered rulebase: %+v %v", rb, err)
+	}
+
+	ssh, err := c.SetAccessRule(ctx, AccessRule{Layer: "Network", Name: "allow ssh", NewPosition: PositionTop(),
+		Enabled: Bool(false)})
+	if err != nil || *ssh.Enabled || ssh.Action.Name != "Accept" {
+		t.Fatalf("Unexpected rule after set: %+v %v", ssh, err)
+	}
+	rb, err = c.ShowAccessRulebase(ctx, ByName("Network"), RulebaseOptions{})
+	if err != nil || rb.Rulebase[0].Rule == nil || rb.Rulebase[0].Rule.Name != "allow ssh" {
+		t.Fatalf("Unexpected rulebase after move: %+v %v", rb, err)
+	}
+
+	if err = c.DeleteAccessRule(ctx, "Network", ByUID(ssh.UID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ShowAccessRule(ctx, "Network", ByUID(ssh.UID)); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+	if s, err := c.SetAccessSection(ctx, AccessSection{Layer: "Network", Name: "Web", Newname: "Web servers"}); err != nil ||
+		s.Name != "Web servers" {
+		t.Fatalf("Unexpected section after set: %+v %v", s, err)
+	}
+	if err = c.DeleteAccessSection(ctx, "Network", ByName("Default")); err != nil {
+		t.Fatal(err)
+	}
+	rb, err = c.ShowAccessRulebase(ctx, ByName("Network"), RulebaseOptions{})
+	if err != nil || len(rb.Rulebase) != 1 || len(rb.Rulebase[0].Section.Rulebase) != 3 {
+		t.Fatalf("Unexpected rulebase after deleting a section: %+v %v", rb, err)
+	}
+
+	l, err := c.CreateAccessLayer(ctx, AccessLayer{Name: "Custom", ApplicationsAndURL: Bool(true)})
+	if err != nil || !*l.ApplicationsAndURL || !*l.Firewall {
+		t.Fatalf("Unexpected layer: %+v %v", l, err)
+	}
+	if rb, err = c.ShowAccessRulebase(ctx, ByUID(l.UID), RulebaseOptions{}); err != nil || rb.Total != 1 {
+		t.Fatalf("Unexpected new layer rulebase: %+v %v", rb, err)
+	}
+	if ll, err := c.ShowAccessLayers(ctx, ListOptions{}); err != nil || ll.Total != 2 || ll.Layers[0].Name != "Custom" {
+		t.Fatalf("Unexpected layers: %+v %v", ll, err)
+	}
+}
+
+func TestFakeNatRulebase(t *testing.T) {
+	c, _ := fakeClient(t)
+	ctx := context.Background()
+
+	if _, err := c.CreateNetwork(ctx, Network{Name: "net-lan", Subnet4: "192.168.1.0", MaskLength4: 24}); err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []Host{{Name: "web1", Ipv4address: "192.168.1.10"}, {Name: "web1-public", Ipv4address: "203.0.113.10"},
+		{Name: "gw-public", Ipv4address: "203.0.113.1"}} {
+		if _, err := c.CreateHost(ctx, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := c.CreateNatSection(ctx, NatSection{Package: "Standard", Name: "Servers", Position: PositionTop()}); err != nil {
+		t.Fatal(err)
+	}
+	static, err := c.CreateNatRule(ctx, NatRule{
+		Package:               "Standard",
+		Name:                  "web1 static",
+		Position:              PositionTopOf("Servers"),
+		Method:                NatMethodStatic,
+		OriginalDestination:   &Member{Name: "web1-public"},
+		TranslatedDestination: &Member{Name: "web1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if static.OriginalSource.Name != "Any" || static.TranslatedDestination.Name != "web1" ||
+		static.TranslatedSource.Name != "Original" || !*static.Enabled {
+		t.Fatalf("Unexpected static rule: %+v", static)
+	}
+	if _, err = c.CreateNatRule(ctx, NatRule{Package: "Standard", Position: PositionBottom(),
+		Method: NatMethodHide, OriginalSource: &Member{Name: "net-lan"}}); err == nil {
+		t.Fatal("Expected hide NAT without a translated source to fail")
+	}
+	if _, err = c.CreateNatRule(ctx, NatRule{Package: "Standard", Name: "lan hide", Position: PositionBottom(),
+		Method: NatMethodHide, OriginalSource: &Member{Name: "net-lan"}, TranslatedSource: &Member{Name: "gw-public"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rb, err := c.ShowNatRulebase(ctx, "Standard", RulebaseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := rb.Rules()
+	if rb.Total != 2 || len(rb.Rulebase) != 1 || rb.Rulebase[0].Section.Name != "Servers" || len(rules) != 2 {
+		t.Fatalf("Unexpected rulebase: %+v", rb)
+	}
+	if rules[1].Name != "lan hide" || rules[1].Method != NatMethodHide || rules[1].OriginalSource.Name != "net-lan" ||
+		rules[1].TranslatedSource.Name != "gw-public" || rules[1].InstallOn[0].Name != "Policy Targets" {
+		t.Fatalf("Unexpected hide rule: %+v", rules[1])
+	}
+	if rb, err = c.ShowNatRulebase(ctx, "Standard", RulebaseOptions{Offset: 1, Limit: 1}); err != nil ||
+		rb.From != 2 || rb.More() || rb.Rules()[0].Name != "lan hide" {
+		t.Fatalf("Unexpected last page: %+v %v", rb, err)
+	}
+
+	if static, err = c.SetNatRule(ctx, NatRule{Package: "Standard", UID: static.UID, NewPosition: PositionBottom(),
+		Enabled: Bool(false)}); err != nil || *static.Enabled || static.TranslatedDestination.Name != "web1" {
+		t.Fatalf("Unexpected rule after set: %+v %v", static, err)
+	}
+	if rb, err = c.ShowNatRulebase(ctx, "Standard", RulebaseOptions{}); err != nil || rb.Rules()[1].UID != static.UID {
+		t.Fatalf("Unexpected rulebase after move: %+v %v", rb, err)
+	}
+	if err = c.DeleteNatRule(ctx, "Standard", ByUID(static.UID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ShowNatRule(ctx, "Standard", ByUID(static.UID)); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+	if s, err := c.SetNatSection(ctx, NatSection{Package: "Standard", Name: "Servers", Newname: "Outbound"}); err != nil ||
+		s.Name != "Outbound" {
+		t.Fatalf("Unexpected section after set: %+v %v", s, err)
+	}
+	if err = c.DeleteNatSection(ctx, "Standard", ByName("Outbound")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ShowNatSection(ctx, "Standard", ByName("Outbound")); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+	if rb, err = c.ShowNatRulebase(ctx, "Standard", RulebaseOptions{}); err != nil || rb.Total != 1 || rb.Rulebase[0].Rule == nil {
+		t.Fatalf("Unexpected rulebase after deleting the section: %+v %v", rb, err)
+	}
+}
+
+func TestFakeWaitForTask(t *testing.T) {
+	c, srv := fakeClient(t)
+	ctx := context.Background()
+	c.conf.TaskInterval = 5 * time.Millisecond
+	c.conf.WaitForPublish = true
+	srv.SetTaskPolls(3)
+
+	if _, err := c.CreateHost(ctx, Host{Name: "web1", Ipv4address: "10.1.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.Published("host", "web1"); !ok {
+		t.Fatal("Expected host to be published")
+	}
+
+	if _, err := c.CreateHost(ctx, Host{Name: "web2", Ipv4address: "10.1.1.2"}); err != nil {
+		t.Fatal(err)
+	}
+	id, err := c.PublishTask(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := c.ShowTasks(ctx, id)
+	if err != nil || len(tasks) != 1 || tasks[0].Done() {
+		t.Fatalf("Expected task in progress, got: %+v %v", tasks, err)
+	}
+	task, err := c.WaitForTask(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != TaskSucceeded || task.ProgressPercentage != 100 ||
+		task.TaskDetails[0].PublishResponse.NumberOfPublishedChanges != 1 {
+		t.Fatalf("Unexpected task: %+v", task)
+	}
+
+	if _, err = c.CreateHost(ctx, Host{Name: "web3", Ipv4address: "10.1.1.3"}); err != nil {
+		t.Fatal(err)
+	}
+	srv.FailTask("Publish failed, the database is locked")
+	err = c.Publish(ctx)
+	var te *TaskError
+	if !errors.As(err, &te) || te.Task.Status != TaskFailed ||
+		te.Task.TaskDetails[0].StatusDescription != "Publish failed, the database is locked" {
+		t.Fatalf("Expected failed task, got: %v", err)
+	}
+	if _, ok := srv.Published("host", "web3"); ok {
+		t.Fatal("Expected host to stay pending after a failed publish")
+	}
+
+	srv.SetTaskPolls(1000)
+	c.conf.TaskTimeout = 30 * time.Millisecond
+	if err = c.Publish(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the wait to time out, got: %v", err)
+	}
+}
+
+func TestFakeInstallPolicy(t *testing.T) {
+	c, srv := fakeClient(t)
+	ctx := context.Background()
+	c.conf.TaskInterval = 5 * time.Millisecond
+	srv.SetTaskPolls(2)
+	srv.AddGateway("gw-east")
+	srv.AddGateway("gw-west")
+
+	if task, err := c.VerifyPolicy(ctx, "Standard"); err != nil || task.Status != TaskSucceeded {
+		t.Fatalf("Unexpected verification: %+v %v", task, err)
+	}
+	task, err := c.InstallPolicy(ctx, InstallPolicyRequest{PolicyPackage: "Standard", Access: Bool(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != TaskSucceeded || len(task.TaskDetails) != 2 || task.TaskDetails[0].GatewayName != "gw-east" {
+		t.Fatalf("Unexpected installation: %+v", task)
+	}
+	if pkg, ok := srv.Installed("gw-west"); !ok || pkg != "Standard" {
+		t.Fatalf("Expected Standard installed on gw-west, got: %s", pkg)
+	}
+
+	srv.FailGateway("gw-west", "Policy installation failed on gateway")
+	task, err = c.InstallPolicy(ctx, InstallPolicyRequest{PolicyPackage: "Standard",
+		InstallOnAllClusterMembersOrFail: Bool(true)})
+	var te *TaskError
+	if !errors.As(err, &te) || task.Status != TaskPartiallySucceeded {
+		t.Fatalf("Expected partial success, got: %+v %v", task, err)
+	}
+	if d := task.TaskDetails[1]; d.GatewayName != "gw-west" || d.StatusCode != "failed" ||
+		!strings.Contains(err.Error(), "gw-west: Policy installation failed on gateway") {
+		t.Fatalf("Unexpected gateway result: %+v %v", d, err)
+	}
+
+	if _, err = c.InstallPolicy(ctx, InstallPolicyRequest{PolicyPackage: "Standard", Targets: []string{"nope"}}); !IsNotFound(err) {
+		t.Fatalf("Expected unknown target, got: %v", err)
+	}
+	if _, err = c.InstallPolicyTask(ctx, InstallPolicyRequest{PolicyPackage: "Missing"}); !IsNotFound(err) {
+		t.Fatalf("Expected unknown package, got: %v", err)
+	}
+	srv.FailTask("Rule 1 hides rule 2")
+	if task, err = c.VerifyPolicy(ctx, "Standard"); !errors.As(err, &te) || task.TaskDetails[0].StatusDescription != "Rule 1 hides rule 2" {
+		t.Fatalf("Expected failed verification, got: %+v %v", task, err)
+	}
+}
+
+func TestFakeSessions(t *testing.T) {
+	c, srv := fakeClient(t)
+	ctx := context.Background()
+	srv.AddUser("bob", "xxxxx")
+
+	if _, err := c.CreateHost(ctx, Host{Name: "web1", Ipv4address: "10.1.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.Discard(ctx); err != nil || n != 1 {
+		t.Fatalf("Expected 1 discarded change, got: %d %v", n, err)
+	}
+	if _, err := c.ShowHost(ctx, ByName("web1")); !IsNotFound(err) {
+		t.Fatalf("Expected discarded host, got: %v", err)
+	}
+	if _, err := c.CreateHost(ctx, Host{Name: "web1", Ipv4address: "10.1.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.KeepAlive(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mine, err := c.ShowSession(ctx, "")
+	if err != nil || mine.UserName != "admin" || mine.Changes != 1 || mine.ExpiredSession {
+		t.Fatalf("Unexpected session: %+v %v", mine, err)
+	}
+	if err = c.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.auth.Current(); ok {
+		t.Fatal("Expected no session after logout")
+	}
+
+	//the session left with changes is taken over by bob
+	bob, err := NewClient(NewAPIConfig(srv.BaseURL(), "bob", "xxxxx", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := bob.ShowSessions(ctx, ListOptions{Filter: "admin"})
+	if err != nil || list.Total != 1 || list.Objects[0].UID != mine.UID || !list.Objects[0].ExpiredSession {
+		t.Fatalf("Unexpected sessions: %+v %v", list, err)
+	}
+	taken, err := bob.TakeOverSession(ctx, mine.UID, false)
+	if err != nil || taken.UserName != "bob" || taken.Changes != 1 {
+		t.Fatalf("Unexpected take over: %+v %v", taken, err)
+	}
+	if err = bob.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.Published("host", "web1"); !ok {
+		t.Fatal("Expected host published by bob")
+	}
+
+	//a session in use is switched to once its client logs out
+	if _, err = c.CreateHost(ctx, Host{Name: "web2", Ipv4address: "10.1.1.2"}); err != nil {
+		t.Fatal(err)
+	}
+	first, err := c.ShowSession(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := NewAPIConfig(srv.BaseURL(), "admin", "vpn12345", "")
+	conf.SetSessionContLast(false)
+	conf.OnClose = ClosePublish
+	c2, err := NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c2.SwitchSession(ctx, first.UID); err == nil {
+		t.Fatal("Expected switching to a session in use to fail")
+	}
+	if _, err = bob.TakeOverSession(ctx, first.UID, false); err == nil {
+		t.Fatal("Expected taking over a session in use to fail")
+	}
+	if err = c.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := c2.SwitchSession(ctx, first.UID); err != nil || s.Changes != 1 {
+		t.Fatalf("Unexpected switch: %+v %v", s, err)
+	}
+	if err = c2.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.Published("host", "web2"); !ok {
+		t.Fatal("Expected host published on close")
+	}
+	if _, ok := c2.auth.Current(); ok {
+		t.Fatal("Expected no session after close")
+	}
+	//closing again does nothing
+	if err = c2.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFakeKeepAlive(t *testing.T) {
+	keepalives := make(chan struct{}, 16)
+	srv := fake.NewServer("admin", "vpn12345")
+	defer srv.Close()
+	conf := NewAPIConfig(srv.BaseURL(), "admin", "vpn12345", "")
+	conf.KeepAlive = true
+	conf.Middleware = []rest.Middleware{func(next rest.Handler) rest.Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := next(req)
+			if err == nil && strings.HasSuffix(req.URL.Path, "/keepalive") {
+				select {
+				case keepalives <- struct{}{}:
+				default:
+				}
+			}
+			return resp, err
+		}
+	}}
+	c, err := NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	//no keepalive without a session
+	if _, ok, err := current[NoMessage, NoMessage](ctx, c, endpointKeepAlive, NoMessage{}); ok || err != nil {
+		t.Fatalf("Expected nothing sent before login, got %v, %v", ok, err)
+	}
+	if err = c.Login(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	stop := c.stop
+	c.mu.Unlock()
+	if stop == nil {
+		t.Fatal("Expected the keepalive loop to start on login")
+	}
+	if n := atomic.LoadInt64(&c.sessTimeout); n != 600 {
+		t.Fatalf("Expected the session timeout of the login, got %d", n)
+	}
+
+	//run a fast loop in place of the one every half timeout
+	c.stopKeepAlive()
+	c.startKeepAlive(time.Millisecond)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-keepalives:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected keepalive %d while logged in", i+1)
+		}
+	}
+	c.mu.Lock()
+	stop = c.stop
+	c.mu.Unlock()
+	if err = c.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stop:
+	default:
+		t.Fatal("Expected the keepalive loop to stop on close")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		t.Fatal("Expected no keepalive loop after close")
+	}
+}
+
+func TestFakeConcurrent(t *testing.T) {
+	var logins int32
+	srv := fake.NewServer("admin", "vpn12345")
+	defer srv.Close()
+	conf := NewAPIConfig(srv.BaseURL(), "admin", "vpn12345", "")
+	conf.Middleware = []rest.Middleware{func(next rest.Handler) rest.Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(req.URL.Path, "/login") {
+				atomic.AddInt32(&logins, 1)
+			}
+			return next(req)
+		}
+	}}
+	c, err := NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	parallel := func(f func(i int) error) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs <- f(i)
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	parallel(func(i int) error {
+		_, err := c.CreateHost(ctx, Host{Name: fmt.Sprintf("web%d", i), Ipv4address: fmt.Sprintf("10.1.1.%d", i+1)})
+		return err
+	})
+	if n := atomic.LoadInt32(&logins); n != 1 {
+		t.Fatalf("Expected a single login, got %d", n)
+	}
+
+	//requests finding the session expired log in again once, and
+	//continue the session with its changes
+	srv.ExpireSessions()
+	parallel(func(i int) error {
+		_, err := c.ShowHost(ctx, ByName(fmt.Sprintf("web%d", i)))
+		return err
+	})
+	if n := atomic.LoadInt32(&logins); n != 2 {
+		t.Fatalf("Expected a single login after expiry, got %d", n)
+	}
+	if err = c.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if _, ok := srv.Published("host", fmt.Sprintf("web%d", i)); !ok {
+			t.Fatalf("Expected web%d to be published", i)
+		}
+	}
+	if err = c.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFakeDomains(t *testing.T) {
+	c, srv := fakeClient(t)
+	ctx := context.Background()
+	cust1 := srv.AddDomain("cust1")
+	cust2 := srv.AddDomain("cust2")
+
+	list, err := c.ShowDomains(ctx, ListOptions{})
+	if err != nil || list.Total != 2 || list.Objects[0].Name != "cust1" || list.Objects[1].UID == "" {
+		t.Fatalf("Unexpected domains: %+v %v", list, err)
+	}
+
+	//a domain logged in to with the credentials
+	d1 := c.Domain("cust1")
+	if _, err = d1.CreateHost(ctx, Host{Name: "web1", Ipv4address: "10.1.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = d1.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cust1.Published("host", "web1"); !ok {
+		t.Fatal("Expected host published in cust1")
+	}
+	if _, ok := srv.Published("host", "web1"); ok {
+		t.Fatal("Expected host not published in the global domain")
+	}
+	if _, err = d1.LoginToDomain(ctx, "cust2"); err == nil {
+		t.Fatal("Expected login-to-domain from a domain to fail")
+	}
+
+	//a domain logged in to from the global session, which logs in
+	//again through the global session once expired
+	d2, err := c.LoginToDomain(ctx, "cust2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d2.CreateHost(ctx, Host{Name: "web2", Ipv4address: "10.1.1.2"}); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireSessions()
+	if _, err = d2.ShowHost(ctx, ByName("web2")); err != nil {
+		t.Fatal(err)
+	}
+	if err = d2.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cust2.Published("host", "web2"); !ok {
+		t.Fatal("Expected host published in cust2")
+	}
+	if _, err = c.LoginToDomain(ctx, "nope"); !IsNotFound(err) {
+		t.Fatalf("Expected unknown domain, got: %v", err)
+	}
+
+	conf := NewAPIConfig(srv.BaseURL(), "admin", "vpn12345", "")
+	conf.SetDomain("cust1")
+	c1, err := NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c1.ShowHost(ctx, ByName("web1")); err != nil {
+		t.Fatal(err)
+	}
+	for _, cl := range []*APIClient{c1, d2, d1, c} {
+		if err = cl.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFakeLoginModes(t *testing.T) {
+	srv := fake.NewServer("admin", "vpn12345")
+	defer srv.Close()
+	srv.AddAPIKey("k3y-0f-svc", "svc")
+	ctx := context.Background()
+
+	//credentials and the session id are never logged
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	c, err := NewClient(NewAPIConfigAPIKey(srv.BaseURL(), "k3y-0f-svc", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.CreateHost(ctx, Host{Name: "web1", Ipv4address: "10.1.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.ShowSession(ctx, "")
+	if err != nil || s.UserName != "svc" {
+		t.Fatalf("Unexpected session: %+v %v", s, err)
+	}
+	sid, _ := c.auth.Current()
+	if err = c.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	bad, err := NewClient(NewAPIConfigAPIKey(srv.BaseURL(), "wrong", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = bad.Login(ctx); err == nil {
+		t.Fatal("Expected login with a wrong api key to fail")
+	}
+
+	conf := NewAPIConfig(srv.BaseURL(), "admin", "vpn12345", "")
+	conf.SetReadOnly(true)
+	ro, err := NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ro.ShowHosts(ctx, ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ro.CreateHost(ctx, Host{Name: "web2", Ipv4address: "10.1.1.2"}); err == nil {
+		t.Fatal("Expected a read only session not to make changes")
+	}
+	if err = ro.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := NewClient(NewAPIConfigRoot(srv.BaseURL(), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err = root.ShowSession(ctx, ""); err != nil || s.UserName != "root" {
+		t.Fatalf("Unexpected root session: %+v %v", s, err)
+	}
+	if err = root.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"k3y-0f-svc", "vpn12345", sid} {
+		if strings.Contains(logged.String(), secret) {
+			t.Fatalf("Secret %q logged: %s", secret, logged.String())
+		}
+	}
+	if str := fmt.Sprint(conf.session); strings.Contains(str, "vpn12345") {
+		t.Fatalf("Password in session string: %s", str)
+	}
+}
+
+func TestFakeCall(t *testing.T) {
+	c, srv := fakeClient(t)
+	ctx := context.Background()
+	srv.AddGateway("gw-east")
+
+	if err := c.Call(ctx, "add-host", Host{Name: "web1", Ipv4address: "10.1.1.1"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	var h Host
+	if err := c.Call(ctx, "show-host", map[string]string{"name": "web1"}, &h); err != nil || h.Ipv4address != "10.1.1.1" {
+		t.Fatalf("Unexpected host: %+v %v", h, err)
+	}
+	raw, err := c.RawCall(ctx, "show-gateways-and-servers", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gws ObjectList[struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}]
+	if err = json.Unmarshal(raw, &gws); err != nil || gws.Total != 1 || gws.Objects[0].Type != "simple-gateway" {
+		t.Fatalf("Unexpected gateways: %s %v", raw, err)
+	}
+
+	if err = c.Call(ctx, "show-host", map[string]string{"name": "nope"}, &h); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+	var he *rest.HTTPError
+	if err = c.Call(ctx, "run-nothing", nil, nil); !errors.As(err, &he) || he.Code != "generic_err_command_not_found" || IsNotFound(err) {
+		t.Fatalf("Expected unknown command, got: %v", err)
+	}
+	if _, err = c.RawCall(ctx, "../login", nil); err == nil {
+		t.Fatal("Expected an invalid command to fail")
+	}
 }
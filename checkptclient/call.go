@@ -0,0 +1,41 @@
+package checkptclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+//commandName matches the names of Check Point commands
+var commandName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+//Call sends payload to a Check Point command not otherwise supported
+//by the client, such as run-script or show-gateways-and-servers, and
+//decodes the response into out. The command is sent in the client's
+//session, logging in as needed, to the versioned base url. A nil
+//payload sends an empty json object, and a nil out ignores the
+//response. Errors are those of the other methods, e.g. IsNotFound
+//reports whether the error is for an object that does not exist.
+func (a *APIClient) Call(ctx context.Context, cmd string, payload any, out any) error {
+	raw, err := a.RawCall(ctx, cmd, payload)
+	if err != nil || out == nil || len(raw) == 0 {
+		return err
+	}
+	if err = json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to transform response message of %s. %v", cmd, err)
+	}
+	return nil
+}
+
+//RawCall sends payload to a Check Point command as Call does and
+//returns the json response undecoded
+func (a *APIClient) RawCall(ctx context.Context, cmd string, payload any) (json.RawMessage, error) {
+	if !commandName.MatchString(cmd) {
+		return nil, fmt.Errorf("InvalidCommand [%s]", cmd)
+	}
+	if payload == nil {
+		payload = NoMessage{}
+	}
+	return command[any, json.RawMessage](ctx, a, cmd, payload)
+}
@@ -0,0 +1,40 @@
+package checkptclient
+
+import "context"
+
+const (
+	endpointShowDomains   = `show-domains`
+	endpointLoginToDomain = `login-to-domain`
+)
+
+//ShowDomains returns a page of the domains of a Multi-Domain Server
+//matching the options
+func (a *APIClient) ShowDomains(ctx context.Context, opts ListOptions) (ObjectList[Domain], error) {
+	return command[ListOptions, ObjectList[Domain]](ctx, a, endpointShowDomains, opts)
+}
+
+//Domain returns a client for the domain with name, or uid, of a
+//Multi-Domain Server, logging in to the domain with the client's
+//credentials and configuration. The clients share the transport but
+//have their own sessions, and each is closed separately.
+func (a *APIClient) Domain(name string) *APIClient {
+	conf := *a.conf
+	conf.session.Domain = name
+	return newClient(&conf, a.httpClient, nil)
+}
+
+//LoginToDomain returns a client for the domain with name, or uid,
+//of a Multi-Domain Server, logging in to the domain through the
+//client's session of the global domain with login-to-domain, as
+//needed by administrators of the global domain only. The clients
+//share the transport, the domain's client logs in again through the
+//global session when its session expires.
+func (a *APIClient) LoginToDomain(ctx context.Context, name string) (*APIClient, error) {
+	conf := *a.conf
+	conf.session.Domain = name
+	d := newClient(&conf, a.httpClient, a)
+	if err := d.Login(ctx); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
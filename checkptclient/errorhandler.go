@@ -2,12 +2,20 @@ package checkptclient
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+
+	"github.com/ericroys/rest"
 )
 
+//ErrHandler is a rest.ErrorHandler for the Check Point service.
+//Unsuccessful responses are returned as *rest.HTTPError with the
+//Check Point error code and messages.
 type ErrHandler struct{}
 
+//Handle implements rest.ErrorHandler
 func (eh ErrHandler) Handle(code int, data []byte) error {
 
 	if code == 200 {
@@ -15,31 +23,81 @@ func (eh ErrHandler) Handle(code int, data []byte) error {
 	}
 	//no special handling based on body
 	if len(data) < 1 {
-		return nil
+		if code < 300 {
+			return nil
+		}
+		return &rest.HTTPError{StatusCode: code}
 	}
 	e := ErrResponse{}
 	json.Unmarshal([]byte(data), &e)
 
-	f := e.Message
+	var f []string
+	if len(e.Message) > 0 {
+		f = append(f, e.Message)
+	}
 
 	log.Printf("error handler: %+v", e)
-	if len(e.Errors) > 0 || len(e.Blocking) > 0 {
+	for _, ef := range e.Errors {
+		f = append(f, ef.Message)
+	}
+	for _, ef := range e.Blocking {
+		f = append(f, ef.Message)
+	}
+	if len(f) > 0 || code >= 300 {
+		return &rest.HTTPError{
+			StatusCode: code,
+			Body:       data,
+			Code:       e.Code,
+			Message:    strings.Join(f, "\n"),
+		}
+	}
+	return nil
+}
 
-		for _, ef := range e.Errors {
-			f = fmt.Sprintf("%s%s\n", f, ef.Message)
+//codeObjectNotFound is the error code for an object that does
+//not exist
+const codeObjectNotFound = "generic_err_object_not_found"
+
+//IsNotFound reports whether err is a Check Point error for an
+//object that does not exist. A 404 for an unknown command is not
+//reported as not found.
+func IsNotFound(err error) bool {
+	var he *rest.HTTPError
+	if !errors.As(err, &he) {
+		return false
+	}
+	if len(he.Code) > 0 {
+		return he.Code == codeObjectNotFound
+	}
+	return he.StatusCode == 404
+}
+
+//TaskError is the error for a task that failed or only partially
+//succeeded. Task holds the details, e.g. the result per gateway of
+//a policy installation.
+type TaskError struct {
+	Task Task
+}
+
+//Error implements error
+func (e *TaskError) Error() string {
+	var f []string
+	for _, d := range e.Task.TaskDetails {
+		msg := d.StatusDescription
+		if len(msg) == 0 {
+			msg = d.StatusCode
+		}
+		if len(msg) == 0 {
+			continue
 		}
-		for _, ef := range e.Blocking {
-			f = fmt.Sprintf("%s%s\n", f, ef.Message)
+		if len(d.GatewayName) > 0 {
+			msg = d.GatewayName + ": " + msg
 		}
+		f = append(f, msg)
 	}
-	if f != "" {
-		return fmt.Errorf(f)
+	s := fmt.Sprintf("task %s [%s] %s", e.Task.TaskName, e.Task.TaskID, e.Task.Status)
+	if len(f) > 0 {
+		s += ": " + strings.Join(f, "; ")
 	}
-	return nil
-	// log.Printf("Error [%v], Message [%s] --> %d", e.Error, e.Message, len(e.Error))
-
-	// if len(e.Error) > 0 {
-	// 	return fmt.Errorf("%d - %s : %s", code, e.Error, e.Message)
-	// }
-	// return nil
+	return s
 }
@@ -0,0 +1,62 @@
+package checkptclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ericroys/rest"
+)
+
+func TestErrHandler(t *testing.T) {
+	data := `{
+		"code" : "generic_err_object_not_found",
+		"message" : "Requested object [bob] not found"
+	  }`
+	err := ErrHandler{}.Handle(404, []byte(data))
+
+	var he *rest.HTTPError
+	if !errors.As(err, &he) {
+		t.Fatalf("Expected *rest.HTTPError, got: %v", err)
+	}
+	if he.Code != "generic_err_object_not_found" || !rest.IsNotFound(err) {
+		t.Fatalf("Unexpected error: %+v", he)
+	}
+
+	data = `{
+		"code" : "err_validation_failed",
+		"message" : "Validation failed with 1 error",
+		"errors" : [ { "message" : "More than one object named 'bob' exists." } ]
+	  }`
+	err = ErrHandler{}.Handle(400, []byte(data))
+	if !errors.As(err, &he) {
+		t.Fatalf("Expected *rest.HTTPError, got: %v", err)
+	}
+	if he.Message != "Validation failed with 1 error\nMore than one object named 'bob' exists." {
+		t.Fatalf("Unexpected message: %s", he.Message)
+	}
+
+	if err = (ErrHandler{}).Handle(200, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		code int
+		data string
+		want bool
+	}{
+		{404, `{"code": "generic_err_object_not_found", "message": "Requested object [bob] not found"}`, true},
+		{404, `{"code": "generic_err_command_not_found", "message": "Unknown command \"show-bob\""}`, false},
+		{400, `{"code": "err_validation_failed", "message": "Validation failed with 1 error"}`, false},
+		{404, ``, true},
+	}
+	for _, tt := range tests {
+		if got := IsNotFound(ErrHandler{}.Handle(tt.code, []byte(tt.data))); got != tt.want {
+			t.Fatalf("%d %s: expected %v", tt.code, tt.data, tt.want)
+		}
+	}
+	if IsNotFound(errors.New("Requested object [bob] not found")) {
+		t.Fatal("Expected only service errors to be not found")
+	}
+}
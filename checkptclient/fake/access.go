@@ -0,0 +1,206 @@
+package fake
+
+import "fmt"
+
+//Uids of the predefined objects referenced by rules
+const (
+	AnyUID           = "97aeb369-9aea-11d5-bd16-0090272ccb30"
+	PolicyTargetsUID = "6c488338-8eec-4103-ad21-cd461ac2c476"
+	OriginalUID      = "85c0f50f-6d8a-4528-88ab-5fb11d8fe16c"
+	NetworkLayerUID  = "c0264a80-1832-4fce-8a90-d0849dc4ba33"
+)
+
+//builtins returns the predefined objects of a new server: the Any
+//object, the policy targets, the Original object of NAT rules and
+//the actions and tracks of rules
+func builtins() []object {
+	objs := []object{
+		{"uid": AnyUID, "name": "Any", "type": "CpmiAnyObject"},
+		{"uid": PolicyTargetsUID, "name": "Policy Targets", "type": "Global"},
+		{"uid": OriginalUID, "name": "Original", "type": "Global"},
+	}
+	for _, a := range []string{"Accept", "Drop", "Reject", "Ask", "Inform", "Apply Layer"} {
+		objs = append(objs, object{"uid": newUID(), "name": a, "type": "RulebaseAction"})
+	}
+	for _, t := range []string{"None", "Log", "Extended Log", "Detailed Log"} {
+		objs = append(objs, object{"uid": newUID(), "name": t, "type": "Track"})
+	}
+	for _, o := range objs {
+		o["read-only"] = true
+		o["color"] = "none"
+	}
+	return objs
+}
+
+//accessCommands registers the commands of access layers and their
+//rulebases, and publishes the Network layer with a cleanup rule
+func (s *Server) accessCommands() {
+	s.objectCommands("access-layer", "access-layers", validateLayer)
+	add := s.commands["add-access-layer"]
+	s.commands["add-access-layer"] = func(sess *session, req request) (interface{}, *Error) {
+		out, e := add(sess, req)
+		if e != nil {
+			return nil, e
+		}
+		layer := s.lookup(sess, out.(object).str("uid"))
+		s.cleanupRule(sess, layer)
+		return s.render(sess, s.lookup(sess, layer.str("uid"))), nil
+	}
+	list := s.commands["show-access-layers"]
+	s.commands["show-access-layers"] = func(sess *session, req request) (interface{}, *Error) {
+		out, e := list(sess, req)
+		if e != nil {
+			return nil, e
+		}
+		m := out.(map[string]interface{})
+		m["access-layers"] = m["objects"]
+		delete(m, "objects")
+		return m, nil
+	}
+	s.rulebaseCommands(accessRulebase)
+
+	//the Network layer is created and published by a session
+	//of the system
+	sys := &session{user: "System", changes: make(map[string]object)}
+	out, _ := s.addObject(sys, "access-layer", request{"name": "Network"}, validateLayer)
+	layer := sys.changes[out.(object).str("uid")]
+	delete(sys.changes, layer.str("uid"))
+	layer["uid"] = NetworkLayerUID
+	sys.changes[NetworkLayerUID] = layer
+	s.cleanupRule(sys, layer)
+	s.publishSystem(sys)
+}
+
+//publishSystem publishes the changes of a session of the system
+//creating the predefined objects
+func (s *Server) publishSystem(sys *session) {
+	for uid, o := range sys.changes {
+		o["meta-info"].(map[string]interface{})["lock"] = "unlocked"
+		s.published[uid] = o
+	}
+}
+
+//accessRulebase is the rulebase of access layers
+var accessRulebase = rulebaseType{
+	rule:      "access-rule",
+	section:   "access-section",
+	container: "access-layer",
+	param:     "layer",
+	validate:  validateAccessRule,
+}
+
+//cleanupRule adds the rule dropping all traffic at the bottom of a
+//new layer
+func (s *Server) cleanupRule(sess *session, layer object) {
+	s.addRule(sess, accessRulebase, "access-rule", request{
+		"layer":    layer.str("uid"),
+		"name":     "Cleanup rule",
+		"position": "bottom",
+		"action":   "Drop",
+		"track":    "None",
+	})
+}
+
+//validateLayer sets the defaults of an access layer
+func validateLayer(s *Server, sess *session, o, _ object) (errs, warns []Message) {
+	defaults := map[string]interface{}{
+		"firewall":                       true,
+		"applications-and-url-filtering": false,
+		"content-awareness":              false,
+		"mobile-access":                  false,
+		"shared":                         false,
+		"implicit-cleanup-action":        "drop",
+		"_rulebase":                      []interface{}{},
+	}
+	for k, v := range defaults {
+		if _, ok := o[k]; !ok {
+			o[k] = v
+		}
+	}
+	return nil, nil
+}
+
+//validateAccessRule resolves the references of an access rule to
+//uids and sets its defaults
+func validateAccessRule(s *Server, sess *session, o, prev object) (errs, warns []Message) {
+	single := func(key, typ, def string) {
+		v, ok := o[key]
+		if !ok {
+			v = def
+		}
+		m, msg := s.member(sess, v)
+		if msg != nil {
+			errs = append(errs, *msg)
+			return
+		}
+		if m.str("type") != typ {
+			errs = append(errs, Message{Message: fmt.Sprintf("Invalid parameter for [%s]. Object [%s] is not a %s",
+				key, m.str("name"), typ)})
+			return
+		}
+		o[key] = m.str("uid")
+	}
+	//the action and track type of rules are stored as uids
+	//already, but are accepted by name too
+	single("action", "RulebaseAction", "Drop")
+	switch t := o["track"].(type) {
+	case nil:
+		o["track"] = map[string]interface{}{"type": "None"}
+	case string:
+		o["track"] = map[string]interface{}{"type": t}
+	}
+	track, _ := o["track"].(map[string]interface{})
+	if track == nil {
+		errs = append(errs, Message{Message: "Invalid parameter for [track]"})
+	} else {
+		if _, ok := track["type"]; !ok {
+			track["type"] = "None"
+		}
+		m, msg := s.member(sess, track["type"])
+		if msg != nil || m.str("type") != "Track" {
+			errs = append(errs, Message{Message: fmt.Sprintf("Invalid parameter for [track]. Unknown track [%v]", track["type"])})
+		} else {
+			track["type"] = m.str("uid")
+		}
+		for _, k := range []string{"per-connection", "per-session", "accounting"} {
+			if _, ok := track[k]; !ok {
+				track[k] = false
+			}
+		}
+	}
+
+	lists := map[string]string{
+		"source":      AnyUID,
+		"destination": AnyUID,
+		"service":     AnyUID,
+		"install-on":  PolicyTargetsUID,
+		"time":        AnyUID,
+	}
+	for key, def := range lists {
+		var cur interface{}
+		if prev != nil {
+			cur = prev[key]
+		}
+		uids, e := s.references(sess, o[key], cur, nil)
+		errs = append(errs, e...)
+		if len(uids) > 1 {
+			uids = remove(uids, AnyUID)
+		}
+		if len(uids) == 0 {
+			uids = []string{def}
+		}
+		o[key] = uidList(uids)
+	}
+	flags := map[string]interface{}{
+		"enabled":            true,
+		"source-negate":      false,
+		"destination-negate": false,
+		"service-negate":     false,
+	}
+	for k, v := range flags {
+		if _, ok := o[k]; !ok {
+			o[k] = v
+		}
+	}
+	return errs, nil
+}
@@ -0,0 +1,82 @@
+package fake
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//AddDomain adds a domain of the Multi-Domain Server and returns its
+//server, e.g. to check the objects published in the domain
+func (s *Server) AddDomain(name string) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := newServer(name, s)
+	d.Server = s.Server
+	d.uid = newUID()
+	s.domains[name] = d
+	return d
+}
+
+//domain returns the server of the domain with name
+func (s *Server) domain(name string) (*Server, *Error) {
+	d, ok := s.domains[name]
+	if !ok {
+		return nil, apiError(http.StatusNotFound, "generic_err_object_not_found",
+			"Domain [%s] not found", name)
+	}
+	return d, nil
+}
+
+//showDomains returns a page of the domains ordered by name
+func (s *Server) showDomains(sess *session, req request) (interface{}, *Error) {
+	limit, offset, e := paging(req)
+	if e != nil {
+		return nil, e
+	}
+	filter := strings.ToLower(req.str("filter"))
+	var objs []object
+	for name, d := range s.domains {
+		o := object{
+			"uid":      d.uid,
+			"name":     name,
+			"type":     "domain",
+			"comments": "",
+			"servers": []interface{}{
+				map[string]interface{}{
+					"name":                name + "_Server",
+					"type":                "domain-server",
+					"multi-domain-server": "MDS",
+					"active":              true,
+				},
+			},
+		}
+		if len(filter) == 0 || o.matches(filter) {
+			objs = append(objs, o)
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool { return objs[i].str("name") < objs[j].str("name") })
+	return page(objs, offset, limit), nil
+}
+
+//loginToDomain starts a session of the user in a domain from a
+//session of the global domain
+func (s *Server) loginToDomain(sess *session, req request) (interface{}, *Error) {
+	if len(s.name) > 0 {
+		return nil, apiError(http.StatusBadRequest, "generic_err_invalid_parameter",
+			"login-to-domain is only allowed from a session of the global domain")
+	}
+	name := req.str("domain")
+	if len(name) == 0 {
+		return nil, errMissing("domain")
+	}
+	d, e := s.domain(name)
+	if e != nil {
+		return nil, e
+	}
+	//a read only session only logs in to read only sessions
+	if sess.readOnly {
+		req["read-only"] = true
+	}
+	return d.start(sess.user, int(sess.timeout.Seconds()), req), nil
+}
@@ -0,0 +1,93 @@
+package fake
+
+import "fmt"
+
+//StandardPackageUID is the uid of the predefined Standard policy
+//package
+const StandardPackageUID = "d2a8f9d3-0a66-4f8a-9c6e-5f9e3d0b6e1a"
+
+//natRulebase is the NAT rulebase of policy packages
+var natRulebase = rulebaseType{
+	rule:      "nat-rule",
+	section:   "nat-section",
+	container: "package",
+	param:     "package",
+	validate:  validateNatRule,
+}
+
+//natCommands registers the commands of NAT rulebases, and publishes
+//the Standard policy package with an empty NAT rulebase
+func (s *Server) natCommands() {
+	s.rulebaseCommands(natRulebase)
+
+	sys := &session{user: "System", changes: make(map[string]object)}
+	sys.changes[StandardPackageUID] = object{
+		"uid":               StandardPackageUID,
+		"name":              "Standard",
+		"type":              "package",
+		"color":             "black",
+		"comments":          "",
+		"access":            true,
+		"threat-prevention": true,
+		"access-layers": []interface{}{
+			map[string]interface{}{"uid": NetworkLayerUID, "name": "Network"},
+		},
+		"installation-targets": "all",
+		"_rulebase":            []interface{}{},
+		"meta-info":            map[string]interface{}{"creator": "System", "last-modifier": "System"},
+	}
+	s.publishSystem(sys)
+}
+
+//natMethods are the valid methods of NAT rules
+var natMethods = []string{"static", "hide", "nat64", "nat46"}
+
+//validateNatRule resolves the references of a NAT rule to uids and
+//sets its defaults. Hide NAT needs a translated source.
+func validateNatRule(s *Server, sess *session, o, prev object) (errs, warns []Message) {
+	if _, ok := o["method"]; !ok {
+		o["method"] = "static"
+	}
+	if !contains(natMethods, o.str("method")) {
+		errs = append(errs, Message{Message: fmt.Sprintf("Invalid parameter for [method]. Unknown method [%v]", o["method"])})
+	}
+	refs := map[string]string{
+		"original-source":        AnyUID,
+		"original-destination":   AnyUID,
+		"original-service":       AnyUID,
+		"translated-source":      OriginalUID,
+		"translated-destination": OriginalUID,
+		"translated-service":     OriginalUID,
+	}
+	for key, def := range refs {
+		v, ok := o[key]
+		if !ok {
+			o[key] = def
+			continue
+		}
+		m, msg := s.member(sess, v)
+		if msg != nil {
+			errs = append(errs, *msg)
+			continue
+		}
+		o[key] = m.str("uid")
+	}
+	if o.str("method") == "hide" && o.str("translated-source") == OriginalUID {
+		errs = append(errs, Message{Message: "Hide NAT requires a translated source"})
+	}
+
+	var cur interface{}
+	if prev != nil {
+		cur = prev["install-on"]
+	}
+	uids, e := s.references(sess, o["install-on"], cur, nil)
+	errs = append(errs, e...)
+	if len(uids) == 0 {
+		uids = []string{PolicyTargetsUID}
+	}
+	o["install-on"] = uidList(uids)
+	if _, ok := o["enabled"]; !ok {
+		o["enabled"] = true
+	}
+	return errs, nil
+}
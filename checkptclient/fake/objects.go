@@ -0,0 +1,668 @@
+package fake
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//validator checks an object before it is stored and returns errors
+//and warnings. The request fails on errors unless ignore-errors is
+//set, and on warnings unless ignore-warnings or ignore-errors is set.
+//prev is the object before a set command and nil for add commands.
+type validator func(s *Server, sess *session, o, prev object) (errs, warns []Message)
+
+//controlParams are request parameters that are not object fields
+var controlParams = map[string]bool{
+	"uid":             true,
+	"new-name":        true,
+	"details-level":   true,
+	"ignore-warnings": true,
+	"ignore-errors":   true,
+	"set-if-exist":    true,
+}
+
+//objectCommands registers the add, show, set and delete commands
+//for objects of type typ, e.g. add-host, and the show command for
+//lists of them named by list, e.g. show-hosts
+func (s *Server) objectCommands(typ, list string, validate validator) {
+	s.commands["add-"+typ] = func(sess *session, req request) (interface{}, *Error) {
+		return s.addObject(sess, typ, req, validate)
+	}
+	s.commands["show-"+typ] = func(sess *session, req request) (interface{}, *Error) {
+		o, e := s.find(sess, typ, req)
+		if e != nil {
+			return nil, e
+		}
+		return s.render(sess, o), nil
+	}
+	s.commands["set-"+typ] = func(sess *session, req request) (interface{}, *Error) {
+		return s.setObject(sess, typ, req, validate)
+	}
+	s.commands["delete-"+typ] = func(sess *session, req request) (interface{}, *Error) {
+		o, e := s.find(sess, typ, req)
+		if e != nil {
+			return nil, e
+		}
+		sess.changes[o.str("uid")] = nil
+		return map[string]string{"message": "OK"}, nil
+	}
+	s.commands["show-"+list] = func(sess *session, req request) (interface{}, *Error) {
+		return s.listObjects(sess, typ, req)
+	}
+}
+
+//listObjects returns a page of the objects of type typ matching
+//the request's filter
+func (s *Server) listObjects(sess *session, typ string, req request) (interface{}, *Error) {
+	limit, offset, e := paging(req)
+	if e != nil {
+		return nil, e
+	}
+	filter := strings.ToLower(req.str("filter"))
+	var objs []object
+	for _, o := range s.objects(sess, typ) {
+		if len(filter) == 0 || o.matches(filter) {
+			objs = append(objs, s.render(sess, o))
+		}
+	}
+	return page(objs, offset, limit), nil
+}
+
+//paging returns the limit and offset of a show command for lists
+func paging(req request) (limit, offset int, e *Error) {
+	limit, offset = req.num("limit", 50), req.num("offset", 0)
+	if limit < 1 || limit > 500 {
+		return 0, 0, apiError(http.StatusBadRequest, "generic_err_invalid_parameter",
+			"Invalid parameter for [limit]. The value must be between 1 and 500")
+	}
+	if offset < 0 {
+		return 0, 0, apiError(http.StatusBadRequest, "generic_err_invalid_parameter",
+			"Invalid parameter for [offset]. The value must be 0 or more")
+	}
+	return limit, offset, nil
+}
+
+//page returns the objects from offset up to limit in the format of
+//the show commands for lists
+func page(objs []object, offset, limit int) map[string]interface{} {
+	total := len(objs)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	out := make([]object, 0, end-offset)
+	for _, o := range objs[offset:end] {
+		out = append(out, o.copy())
+	}
+	from := 0
+	if len(out) > 0 {
+		from = offset + 1
+	}
+	return map[string]interface{}{
+		"from":    from,
+		"to":      offset + len(out),
+		"total":   total,
+		"objects": out,
+	}
+}
+
+//addObject stores a new object from the request as a pending change
+func (s *Server) addObject(sess *session, typ string, req request, validate validator) (interface{}, *Error) {
+	name := req.str("name")
+	if len(name) == 0 {
+		return nil, errMissing("name")
+	}
+	o := object{
+		"uid":      newUID(),
+		"name":     name,
+		"type":     typ,
+		"color":    "black",
+		"comments": "",
+		"tags":     []interface{}{},
+		"domain": map[string]interface{}{
+			"uid":         "41e821a0-3720-11e3-aa6e-0800200c9fde",
+			"name":        "SMC User",
+			"domain-type": "domain",
+		},
+		"read-only": false,
+	}
+	for k, v := range req {
+		if !controlParams[k] {
+			o[k] = v
+		}
+	}
+	o["meta-info"] = map[string]interface{}{
+		"lock":             "locked by current session",
+		"validation-state": "ok",
+		"creator":          sess.user,
+		"last-modifier":    sess.user,
+	}
+	if e := s.check(sess, o, nil, req, validate); e != nil {
+		return nil, e
+	}
+	sess.changes[o.str("uid")] = o
+	return s.render(sess, o), nil
+}
+
+//setObject applies the request to an existing object as a pending
+//change
+func (s *Server) setObject(sess *session, typ string, req request, validate validator) (interface{}, *Error) {
+	cur, e := s.find(sess, typ, req)
+	if e != nil {
+		return nil, e
+	}
+	o := cur.copy()
+	for k, v := range req {
+		if !controlParams[k] && k != "name" {
+			o[k] = v
+		}
+	}
+	if n := req.str("new-name"); len(n) > 0 {
+		o["name"] = n
+	}
+	meta, _ := o["meta-info"].(map[string]interface{})
+	if meta != nil {
+		meta["lock"] = "locked by current session"
+		meta["last-modifier"] = sess.user
+	}
+	if e := s.check(sess, o, cur, req, validate); e != nil {
+		return nil, e
+	}
+	sess.changes[o.str("uid")] = o
+	return s.render(sess, o), nil
+}
+
+//check validates an object, including that its name is unique
+func (s *Server) check(sess *session, o, prev object, req request, validate validator) *Error {
+	var errs, warns []Message
+	for _, v := range s.objects(sess, "") {
+		if rulebaseObjects[o.str("type")] || rulebaseObjects[v.str("type")] {
+			continue
+		}
+		if v.str("name") == o.str("name") && v.str("uid") != o.str("uid") {
+			errs = append(errs, Message{Message: fmt.Sprintf("More than one object named '%s' exists.", o.str("name"))})
+			break
+		}
+	}
+	if validate != nil {
+		e, w := validate(s, sess, o, prev)
+		errs = append(errs, e...)
+		warns = append(warns, w...)
+	}
+	if req.flag("ignore-errors") {
+		return nil
+	}
+	if req.flag("ignore-warnings") {
+		warns = nil
+	}
+	if len(errs) > 0 || len(warns) > 0 {
+		return errValidation(errs, warns)
+	}
+	return nil
+}
+
+//refFields are the fields of objects holding references to other
+//objects by uid
+var refFields = map[string]bool{
+	"members":     true,
+	"source":      true,
+	"destination": true,
+	"service":     true,
+	"install-on":  true,
+	"time":        true,
+	"action":      true,
+
+	"original-source":        true,
+	"original-destination":   true,
+	"original-service":       true,
+	"translated-source":      true,
+	"translated-destination": true,
+	"translated-service":     true,
+}
+
+//rulebaseObjects are the types of rules and sections. They are
+//referenced by uid only and their names need not be unique.
+var rulebaseObjects = map[string]bool{
+	"access-rule":    true,
+	"access-section": true,
+	"nat-rule":       true,
+	"nat-section":    true,
+}
+
+//render returns a copy of an object for a response, with references
+//expanded to objects and internal fields, prefixed with _, removed
+func (s *Server) render(sess *session, o object) object {
+	c := o.copy()
+	for k, v := range c {
+		switch {
+		case strings.HasPrefix(k, "_"):
+			delete(c, k)
+		case refFields[k]:
+			c[k] = s.expand(sess, v)
+		}
+	}
+	if t, ok := c["track"].(map[string]interface{}); ok {
+		t["type"] = s.expand(sess, t["type"])
+	}
+	return c
+}
+
+//expand replaces a uid, or a list of uids, with the uid, name and
+//type of the referenced objects. Deleted objects are dropped from
+//lists.
+func (s *Server) expand(sess *session, v interface{}) interface{} {
+	switch r := v.(type) {
+	case string:
+		if o := s.lookup(sess, r); o != nil {
+			return reference(o)
+		}
+	case []interface{}:
+		out := make([]interface{}, 0, len(r))
+		for _, u := range r {
+			uid, _ := u.(string)
+			if o := s.lookup(sess, uid); o != nil {
+				out = append(out, reference(o))
+			}
+		}
+		return out
+	}
+	return v
+}
+
+//reference returns the uid, name and type of an object
+func reference(o object) map[string]interface{} {
+	return map[string]interface{}{
+		"uid":  o.str("uid"),
+		"name": o.str("name"),
+		"type": o.str("type"),
+	}
+}
+
+//lookup returns the object with uid as seen by the session, nil if
+//it does not exist or the session deleted it
+func (s *Server) lookup(sess *session, uid string) object {
+	if o, ok := sess.changes[uid]; ok {
+		return o
+	}
+	return s.published[uid]
+}
+
+//objects returns the objects of type typ, or all types if typ is
+//empty, as seen by the session, ordered by name
+func (s *Server) objects(sess *session, typ string) []object {
+	var out []object
+	for uid, o := range s.published {
+		if _, ok := sess.changes[uid]; !ok && (len(typ) == 0 || o.str("type") == typ) {
+			out = append(out, o)
+		}
+	}
+	for _, o := range sess.changes {
+		if o != nil && (len(typ) == 0 || o.str("type") == typ) {
+			out = append(out, o)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].str("name") < out[j].str("name")
+	})
+	return out
+}
+
+//find returns the object of type typ identified by the request's
+//uid or name
+func (s *Server) find(sess *session, typ string, req request) (object, *Error) {
+	if uid := req.str("uid"); len(uid) > 0 {
+		o := s.lookup(sess, uid)
+		if o == nil || o.str("type") != typ {
+			return nil, errNotFound(uid)
+		}
+		return o, nil
+	}
+	name := req.str("name")
+	if len(name) == 0 {
+		return nil, errMissing("uid or name")
+	}
+	for _, o := range s.objects(sess, typ) {
+		if o.str("name") == name {
+			return o, nil
+		}
+	}
+	return nil, errNotFound(name)
+}
+
+//validateHost requires a valid ip address and warns when another
+//host has the same address
+func validateHost(s *Server, sess *session, o, _ object) (errs, warns []Message) {
+	if ip := o.str("ip-address"); len(ip) > 0 {
+		delete(o, "ip-address")
+		if p := net.ParseIP(ip); p != nil && p.To4() == nil {
+			o["ipv6-address"] = ip
+		} else {
+			o["ipv4-address"] = ip
+		}
+	}
+	ip4, ip6 := o.str("ipv4-address"), o.str("ipv6-address")
+	if len(ip4) == 0 && len(ip6) == 0 {
+		return []Message{{Message: "Missing parameter: [ip-address]"}}, nil
+	}
+	if len(ip4) > 0 && (net.ParseIP(ip4) == nil || net.ParseIP(ip4).To4() == nil) {
+		errs = append(errs, Message{Message: fmt.Sprintf("Invalid IPv4 address [%s]", ip4)})
+	}
+	if len(ip6) > 0 && net.ParseIP(ip6) == nil {
+		errs = append(errs, Message{Message: fmt.Sprintf("Invalid IPv6 address [%s]", ip6)})
+	}
+	if _, ok := o["nat-settings"]; !ok {
+		o["nat-settings"] = map[string]interface{}{"auto-rule": false}
+	}
+	if _, ok := o["interfaces"]; !ok {
+		o["interfaces"] = []interface{}{}
+	}
+	if _, ok := o["groups"]; !ok {
+		o["groups"] = []interface{}{}
+	}
+
+	for _, h := range s.objects(sess, "host") {
+		if h.str("uid") == o.str("uid") {
+			continue
+		}
+		for _, ip := range []string{ip4, ip6} {
+			if len(ip) > 0 && (h.str("ipv4-address") == ip || h.str("ipv6-address") == ip) {
+				warns = append(warns, Message{Message: fmt.Sprintf("More than one host have the same IP %s", ip)})
+			}
+		}
+	}
+	return errs, warns
+}
+
+//validateNetwork requires a valid subnet and mask length. A subnet
+//and mask-length are stored as IPv4 or IPv6 by the subnet address.
+func validateNetwork(s *Server, sess *session, o, _ object) (errs, warns []Message) {
+	if sub := o.str("subnet"); len(sub) > 0 {
+		suffix := "4"
+		if p := net.ParseIP(sub); p != nil && p.To4() == nil {
+			suffix = "6"
+		}
+		o["subnet"+suffix] = sub
+		if ml, ok := o["mask-length"]; ok {
+			o["mask-length"+suffix] = ml
+		}
+		delete(o, "subnet")
+		delete(o, "mask-length")
+	}
+	sub4, sub6 := o.str("subnet4"), o.str("subnet6")
+	if len(sub4) == 0 && len(sub6) == 0 {
+		return []Message{{Message: "Missing parameter: [subnet]"}}, nil
+	}
+	if len(sub4) > 0 {
+		if p := net.ParseIP(sub4); p == nil || p.To4() == nil {
+			errs = append(errs, Message{Message: fmt.Sprintf("Invalid IPv4 address [%s]", sub4)})
+		}
+		if ml, ok := o["mask-length4"].(float64); !ok || ml < 0 || ml > 32 {
+			errs = append(errs, Message{Message: "Invalid parameter for [mask-length4]. The value must be between 0 and 32"})
+		}
+	}
+	if len(sub6) > 0 {
+		if net.ParseIP(sub6) == nil {
+			errs = append(errs, Message{Message: fmt.Sprintf("Invalid IPv6 address [%s]", sub6)})
+		}
+		if ml, ok := o["mask-length6"].(float64); !ok || ml < 0 || ml > 128 {
+			errs = append(errs, Message{Message: "Invalid parameter for [mask-length6]. The value must be between 0 and 128"})
+		}
+	}
+	if _, ok := o["broadcast"]; !ok && len(sub4) > 0 {
+		o["broadcast"] = "allow"
+	}
+	if _, ok := o["nat-settings"]; !ok {
+		o["nat-settings"] = map[string]interface{}{"auto-rule": false}
+	}
+	if _, ok := o["groups"]; !ok {
+		o["groups"] = []interface{}{}
+	}
+	return errs, nil
+}
+
+//validateRange requires the first and last addresses of an IPv4
+//and/or IPv6 range, in order
+func validateRange(s *Server, sess *session, o, _ object) (errs, warns []Message) {
+	for _, end := range []string{"first", "last"} {
+		if ip := o.str("ip-address-" + end); len(ip) > 0 {
+			key := "ipv4-address-" + end
+			if p := net.ParseIP(ip); p != nil && p.To4() == nil {
+				key = "ipv6-address-" + end
+			}
+			o[key] = ip
+			delete(o, "ip-address-"+end)
+		}
+	}
+	found := false
+	for _, v := range []string{"ipv4", "ipv6"} {
+		first, last := o.str(v+"-address-first"), o.str(v+"-address-last")
+		if len(first) == 0 && len(last) == 0 {
+			continue
+		}
+		found = true
+		if len(first) == 0 || len(last) == 0 {
+			errs = append(errs, Message{Message: fmt.Sprintf("Missing parameter: [%s-address-first] or [%s-address-last]", v, v)})
+			continue
+		}
+		a, b := net.ParseIP(first), net.ParseIP(last)
+		if a == nil || b == nil || (v == "ipv4") != (a.To4() != nil) || (v == "ipv4") != (b.To4() != nil) {
+			errs = append(errs, Message{Message: fmt.Sprintf("Invalid %s address range [%s - %s]", strings.ToUpper(v[:2])+v[2:], first, last)})
+			continue
+		}
+		if bytes.Compare(a.To16(), b.To16()) > 0 {
+			errs = append(errs, Message{Message: fmt.Sprintf("First address [%s] is greater than last address [%s]", first, last)})
+		}
+	}
+	if !found {
+		return []Message{{Message: "Missing parameter: [ip-address-first]"}}, nil
+	}
+	if _, ok := o["nat-settings"]; !ok {
+		o["nat-settings"] = map[string]interface{}{"auto-rule": false}
+	}
+	if _, ok := o["groups"]; !ok {
+		o["groups"] = []interface{}{}
+	}
+	return errs, nil
+}
+
+//groupValidator returns a validator for groups whose members must
+//be of one of the given types
+func groupValidator(types ...string) validator {
+	return func(s *Server, sess *session, o, prev object) (errs, warns []Message) {
+		var cur interface{}
+		if prev != nil {
+			cur = prev["members"]
+		}
+		uids, errs := s.references(sess, o["members"], cur, types)
+		if contains(uids, o.str("uid")) {
+			errs = append(errs, Message{Message: "A group cannot contain itself"})
+		}
+		o["members"] = uidList(uids)
+		if _, ok := o["groups"]; !ok {
+			o["groups"] = []interface{}{}
+		}
+		return errs, nil
+	}
+}
+
+//references resolves references to objects, a list of or a single
+//uid or name, to uids. On set commands the value may also be
+//{"add": [...], "remove": [...]} to change prev, the current list
+//of uids. Added objects must be of one of types, if any are given.
+func (s *Server) references(sess *session, v, prev interface{}, types []string) (uids []string, errs []Message) {
+	add := func(refs interface{}, check bool) {
+		list, ok := refs.([]interface{})
+		if !ok && refs != nil {
+			list = []interface{}{refs}
+		}
+		for _, ref := range list {
+			m, msg := s.member(sess, ref)
+			if msg != nil {
+				errs = append(errs, *msg)
+				continue
+			}
+			if check && len(types) > 0 && !contains(types, m.str("type")) {
+				errs = append(errs, Message{Message: fmt.Sprintf("Object [%s] of type [%s] is not valid here",
+					m.str("name"), m.str("type"))})
+				continue
+			}
+			if !contains(uids, m.str("uid")) {
+				uids = append(uids, m.str("uid"))
+			}
+		}
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok || (m["add"] == nil && m["remove"] == nil) {
+		add(v, true)
+		return uids, errs
+	}
+	cur, _ := prev.([]interface{})
+	for _, u := range cur {
+		uids = append(uids, u.(string))
+	}
+	add(m["add"], true)
+	if rm, ok := m["remove"]; ok {
+		keep := uids
+		uids = nil
+		add(rm, false)
+		drop := uids
+		uids = nil
+		for _, u := range keep {
+			if !contains(drop, u) {
+				uids = append(uids, u)
+			}
+		}
+	}
+	return uids, errs
+}
+
+//uidList returns uids as a list of a stored object
+func uidList(uids []string) []interface{} {
+	out := make([]interface{}, 0, len(uids))
+	for _, u := range uids {
+		out = append(out, u)
+	}
+	return out
+}
+
+//member returns the object referenced by a group member, given as
+//a uid, a name or an object with either
+func (s *Server) member(sess *session, ref interface{}) (object, *Message) {
+	var id string
+	switch r := ref.(type) {
+	case string:
+		id = r
+	case map[string]interface{}:
+		id, _ = r["uid"].(string)
+		if len(id) == 0 {
+			id, _ = r["name"].(string)
+		}
+	}
+	if o := s.lookup(sess, id); o != nil {
+		return o, nil
+	}
+	for _, o := range s.objects(sess, "") {
+		if o.str("name") == id && !rulebaseObjects[o.str("type")] {
+			return o, nil
+		}
+	}
+	return nil, &Message{Message: fmt.Sprintf("Requested object [%s] not found", id)}
+}
+
+//contains reports whether list contains v
+func contains(list []string, v string) bool {
+	for _, u := range list {
+		if u == v {
+			return true
+		}
+	}
+	return false
+}
+
+//portRange matches the port formats of TCP and UDP services, a
+//port, a range of ports or a comparison
+var portRange = regexp.MustCompile(`^(?:(\d+)|(\d+)-(\d+)|[<>]=?(\d+))$`)
+
+//serviceValidator returns a validator for TCP or UDP services that
+//requires a valid port and sets the defaults with the session
+//timeout in seconds
+func serviceValidator(timeout int) validator {
+	return func(s *Server, sess *session, o, _ object) (errs, warns []Message) {
+		for _, key := range []string{"port", "source-port"} {
+			p := o.str(key)
+			if len(p) == 0 {
+				if key == "port" {
+					errs = append(errs, Message{Message: "Missing parameter: [port]"})
+				}
+				continue
+			}
+			m := portRange.FindStringSubmatch(p)
+			ok := m != nil
+			for i := 1; ok && i < len(m); i++ {
+				if v, err := strconv.Atoi(m[i]); len(m[i]) > 0 && (err != nil || v < 1 || v > 65535) {
+					ok = false
+				}
+			}
+			if ok && len(m[2]) > 0 {
+				first, _ := strconv.Atoi(m[2])
+				last, _ := strconv.Atoi(m[3])
+				ok = first <= last
+			}
+			if !ok {
+				errs = append(errs, Message{Message: fmt.Sprintf("Invalid parameter for [%s]. Invalid port [%s]", key, p)})
+			}
+		}
+		if t, ok := o["session-timeout"].(float64); ok && (t < 0 || t > 86400) {
+			errs = append(errs, Message{Message: "Invalid parameter for [session-timeout]. The value must be between 0 and 86400"})
+		}
+		defaults := map[string]interface{}{
+			"session-timeout":                                 timeout,
+			"use-default-session-timeout":                     true,
+			"match-for-any":                                   true,
+			"keep-connections-open-after-policy-installation": false,
+			"sync-connections-on-cluster":                     true,
+			"groups":                                          []interface{}{},
+		}
+		if o.str("type") == "service-udp" {
+			defaults["accept-replies"] = false
+		}
+		for k, v := range defaults {
+			if _, ok := o[k]; !ok {
+				o[k] = v
+			}
+		}
+		return errs, nil
+	}
+}
+
+//validateICMP requires an ICMP type and code in the range of ICMP
+func validateICMP(s *Server, sess *session, o, _ object) (errs, warns []Message) {
+	if _, ok := o["icmp-type"]; !ok {
+		errs = append(errs, Message{Message: "Missing parameter: [icmp-type]"})
+	}
+	if _, ok := o["icmp-code"]; !ok {
+		o["icmp-code"] = 0
+	}
+	for _, key := range []string{"icmp-type", "icmp-code"} {
+		if v, ok := o[key].(float64); ok && (v < 0 || v > 255) {
+			errs = append(errs, Message{Message: fmt.Sprintf("Invalid parameter for [%s]. The value must be between 0 and 255", key)})
+		}
+	}
+	if _, ok := o["keep-connections-open-after-policy-installation"]; !ok {
+		o["keep-connections-open-after-policy-installation"] = false
+	}
+	if _, ok := o["groups"]; !ok {
+		o["groups"] = []interface{}{}
+	}
+	return errs, nil
+}
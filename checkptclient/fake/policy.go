@@ -0,0 +1,135 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+)
+
+//AddGateway publishes a gateway, an installation target for policy
+//packages, and returns its uid
+func (s *Server) AddGateway(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uid := newUID()
+	s.published[uid] = object{
+		"uid":          uid,
+		"name":         name,
+		"type":         "simple-gateway",
+		"color":        "black",
+		"comments":     "",
+		"ipv4-address": "",
+		"meta-info":    map[string]interface{}{"creator": "System", "last-modifier": "System"},
+	}
+	return uid
+}
+
+//FailGateway makes policy installations on the gateway with name
+//fail with the status description msg
+func (s *Server) FailGateway(name, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gatewayFailures[name] = msg
+}
+
+//Installed returns the name of the policy package last installed on
+//the gateway with name
+func (s *Server) Installed(gateway string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pkg, ok := s.installed[gateway]
+	return pkg, ok
+}
+
+//policyPackage returns the package named by the request's
+//policy-package
+func (s *Server) policyPackage(sess *session, req request) (object, *Error) {
+	name := req.str("policy-package")
+	if len(name) == 0 {
+		return nil, errMissing("policy-package")
+	}
+	return s.find(sess, "package", request{"name": name})
+}
+
+//installPolicy installs a package on the requested targets, by
+//default all gateways, with a result per gateway
+func (s *Server) installPolicy(sess *session, req request) (interface{}, *Error) {
+	pkg, e := s.policyPackage(sess, req)
+	if e != nil {
+		return nil, e
+	}
+	var gateways []object
+	if targets, ok := req["targets"]; ok {
+		list, ok := targets.([]interface{})
+		if !ok {
+			list = []interface{}{targets}
+		}
+		for _, t := range list {
+			gw, msg := s.member(sess, t)
+			if msg != nil {
+				return nil, errNotFound(fmt.Sprint(t))
+			}
+			if gw.str("type") != "simple-gateway" {
+				return nil, apiError(http.StatusBadRequest, "generic_err_invalid_parameter",
+					"Invalid parameter for [targets]. Object [%s] is not a gateway", gw.str("name"))
+			}
+			gateways = append(gateways, gw)
+		}
+	} else {
+		gateways = s.objects(sess, "simple-gateway")
+	}
+	if len(gateways) == 0 {
+		return nil, apiError(http.StatusBadRequest, "generic_err_invalid_parameter",
+			"No installation targets for policy package [%s]", pkg.str("name"))
+	}
+
+	queued, failAll := s.taskFailure()
+	var details []interface{}
+	failed := 0
+	for _, gw := range gateways {
+		name := gw.str("name")
+		code, desc := "succeeded", "Policy installation succeeded"
+		if msg, ok := s.gatewayFailures[name]; ok || failAll {
+			if !ok {
+				msg = queued
+			}
+			code, desc = "failed", msg
+			failed++
+		} else if !req.flag("prepare-only") {
+			s.installed[name] = pkg.str("name")
+		}
+		details = append(details, map[string]interface{}{
+			"uid":               newUID(),
+			"gatewayId":         gw.str("uid"),
+			"gatewayName":       name,
+			"statusCode":        code,
+			"statusDescription": desc,
+			"taskNotification":  newUID(),
+		})
+	}
+	status := "succeeded"
+	switch {
+	case failed == len(gateways):
+		status = "failed"
+	case failed > 0:
+		status = "partially succeeded"
+	}
+	id := s.newTask("Policy installation - "+pkg.str("name"), status, details)
+	return map[string]string{"task-id": id}, nil
+}
+
+//verifyPolicy verifies a package, failing if a task failure is
+//queued
+func (s *Server) verifyPolicy(sess *session, req request) (interface{}, *Error) {
+	pkg, e := s.policyPackage(sess, req)
+	if e != nil {
+		return nil, e
+	}
+	status, code, desc := "succeeded", "succeeded", "Verification completed successfully"
+	if msg, ok := s.taskFailure(); ok {
+		status, code, desc = "failed", "failed", msg
+	}
+	id := s.newTask("Verify policy - "+pkg.str("name"), status, []interface{}{
+		map[string]interface{}{"statusCode": code, "statusDescription": desc},
+	})
+	return map[string]string{"task-id": id}, nil
+}
@@ -0,0 +1,459 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+//rulebaseType describes a kind of rulebase, the rules and sections
+//of an access layer or of the NAT policy of a package
+type rulebaseType struct {
+	//rule and section are the types of the rules and sections
+	rule, section string
+	//container is the type of the objects holding rulebases
+	container string
+	//param is the request parameter naming the container
+	param string
+	//validate checks rules and sets their defaults
+	validate validator
+}
+
+//rulebaseCommands registers the add, show, set and delete commands
+//for rules and sections, e.g. add-access-rule, and the show command
+//for the rulebase, e.g. show-access-rulebase
+func (s *Server) rulebaseCommands(rt rulebaseType) {
+	s.commands["add-"+rt.rule] = func(sess *session, req request) (interface{}, *Error) {
+		return s.addRule(sess, rt, rt.rule, req)
+	}
+	s.commands["add-"+rt.section] = func(sess *session, req request) (interface{}, *Error) {
+		return s.addRule(sess, rt, rt.section, req)
+	}
+	for _, typ := range []string{rt.rule, rt.section} {
+		typ := typ
+		s.commands["show-"+typ] = func(sess *session, req request) (interface{}, *Error) {
+			_, o, e := s.findRule(sess, rt, typ, req)
+			if e != nil {
+				return nil, e
+			}
+			return s.render(sess, o), nil
+		}
+		s.commands["set-"+typ] = func(sess *session, req request) (interface{}, *Error) {
+			return s.setRule(sess, rt, typ, req)
+		}
+		s.commands["delete-"+typ] = func(sess *session, req request) (interface{}, *Error) {
+			c, o, e := s.findRule(sess, rt, typ, req)
+			if e != nil {
+				return nil, e
+			}
+			s.setOrder(sess, c, remove(order(c), o.str("uid")))
+			sess.changes[o.str("uid")] = nil
+			return map[string]string{"message": "OK"}, nil
+		}
+	}
+	s.commands["show-"+strings.TrimSuffix(rt.rule, "-rule")+"-rulebase"] = func(sess *session, req request) (interface{}, *Error) {
+		return s.showRulebase(sess, rt, req)
+	}
+}
+
+//order returns the uids of the rules and sections of a container,
+//in order. A section holds the rules up to the next section.
+func order(c object) []string {
+	l, _ := c["_rulebase"].([]interface{})
+	out := make([]string, 0, len(l))
+	for _, u := range l {
+		out = append(out, u.(string))
+	}
+	return out
+}
+
+//setOrder stores the order of the rulebase of a container as a
+//pending change
+func (s *Server) setOrder(sess *session, c object, uids []string) {
+	c = c.copy()
+	c["_rulebase"] = uidList(uids)
+	sess.changes[c.str("uid")] = c
+}
+
+//remove returns uids without uid
+func remove(uids []string, uid string) []string {
+	out := make([]string, 0, len(uids))
+	for _, u := range uids {
+		if u != uid {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+//container returns the object holding the rulebase named by the
+//request's container parameter
+func (s *Server) container(sess *session, rt rulebaseType, req request) (object, *Error) {
+	id := req.str(rt.param)
+	if len(id) == 0 {
+		return nil, errMissing(rt.param)
+	}
+	if o := s.lookup(sess, id); o != nil && o.str("type") == rt.container {
+		return o, nil
+	}
+	return s.find(sess, rt.container, request{"name": id})
+}
+
+//findRule returns the container and the rule or section of type
+//typ identified by the request's uid, name or rule-number
+func (s *Server) findRule(sess *session, rt rulebaseType, typ string, req request) (object, object, *Error) {
+	c, e := s.container(sess, rt, req)
+	if e != nil {
+		return nil, nil, e
+	}
+	uid, name, num := req.str("uid"), req.str("name"), req.num("rule-number", 0)
+	if len(uid) == 0 && len(name) == 0 && num == 0 {
+		return nil, nil, errMissing("uid or name")
+	}
+	n := 0
+	for _, u := range order(c) {
+		o := s.lookup(sess, u)
+		if o == nil {
+			continue
+		}
+		if o.str("type") == rt.rule {
+			n++
+		}
+		if o.str("type") != typ {
+			continue
+		}
+		if (len(uid) > 0 && u == uid) || (len(uid) == 0 && len(name) > 0 && o.str("name") == name) ||
+			(len(uid) == 0 && len(name) == 0 && typ == rt.rule && n == num) {
+			return c, o, nil
+		}
+	}
+	id := uid
+	if len(id) == 0 {
+		id = name
+	}
+	if len(id) == 0 {
+		id = fmt.Sprint(num)
+	}
+	return nil, nil, errNotFound(id)
+}
+
+//addRule adds a rule or section, of type typ, to a rulebase at the
+//request's position
+func (s *Server) addRule(sess *session, rt rulebaseType, typ string, req request) (interface{}, *Error) {
+	c, e := s.container(sess, rt, req)
+	if e != nil {
+		return nil, e
+	}
+	pos, ok := req["position"]
+	if !ok {
+		return nil, errMissing("position")
+	}
+	o := object{
+		"uid":      newUID(),
+		"name":     req.str("name"),
+		"type":     typ,
+		"comments": "",
+		rt.param:   c.str("uid"),
+	}
+	for k, v := range req {
+		if !controlParams[k] && k != rt.param && k != "position" {
+			o[k] = v
+		}
+	}
+	o["meta-info"] = map[string]interface{}{
+		"lock":          "locked by current session",
+		"creator":       sess.user,
+		"last-modifier": sess.user,
+	}
+	uids := order(c)
+	i, e := s.position(sess, rt, uids, pos)
+	if e != nil {
+		return nil, e
+	}
+	if typ == rt.rule {
+		if e = s.check(sess, o, nil, req, rt.validate); e != nil {
+			return nil, e
+		}
+	}
+	uids = append(uids[:i], append([]string{o.str("uid")}, uids[i:]...)...)
+	s.setOrder(sess, c, uids)
+	sess.changes[o.str("uid")] = o
+	return s.render(sess, o), nil
+}
+
+//setRule changes a rule or section, moving it to the request's
+//new-position if given
+func (s *Server) setRule(sess *session, rt rulebaseType, typ string, req request) (interface{}, *Error) {
+	c, cur, e := s.findRule(sess, rt, typ, req)
+	if e != nil {
+		return nil, e
+	}
+	o := cur.copy()
+	for k, v := range req {
+		if !controlParams[k] && k != rt.param && k != "name" && k != "new-position" && k != "rule-number" {
+			o[k] = v
+		}
+	}
+	if n := req.str("new-name"); len(n) > 0 {
+		o["name"] = n
+	}
+	if meta, _ := o["meta-info"].(map[string]interface{}); meta != nil {
+		meta["lock"] = "locked by current session"
+		meta["last-modifier"] = sess.user
+	}
+	if typ == rt.rule {
+		if e = s.check(sess, o, cur, req, rt.validate); e != nil {
+			return nil, e
+		}
+	}
+	if pos, ok := req["new-position"]; ok {
+		uids := remove(order(c), o.str("uid"))
+		i, e := s.position(sess, rt, uids, pos)
+		if e != nil {
+			return nil, e
+		}
+		s.setOrder(sess, c, append(uids[:i], append([]string{o.str("uid")}, uids[i:]...)...))
+	}
+	sess.changes[o.str("uid")] = o
+	return s.render(sess, o), nil
+}
+
+//position returns the index in the rulebase order uids for a
+//position, a rule number, "top", "bottom" or one of {"above": id},
+//{"below": id}, {"top": section} and {"bottom": section}
+func (s *Server) position(sess *session, rt rulebaseType, uids []string, pos interface{}) (int, *Error) {
+	invalid := apiError(http.StatusBadRequest, "generic_err_invalid_parameter",
+		"Invalid parameter for [position]")
+	//index returns the index of the rule or section id, and
+	//the index of the end of the section if it is one
+	index := func(id string) (int, int, *Error) {
+		for i, u := range uids {
+			o := s.lookup(sess, u)
+			if o == nil || (u != id && o.str("name") != id) {
+				continue
+			}
+			if o.str("type") != rt.section {
+				return i, i + 1, nil
+			}
+			end := i + 1
+			for ; end < len(uids); end++ {
+				if n := s.lookup(sess, uids[end]); n != nil && n.str("type") == rt.section {
+					break
+				}
+			}
+			return i, end, nil
+		}
+		return 0, 0, errNotFound(id)
+	}
+
+	switch p := pos.(type) {
+	case float64:
+		n := int(p)
+		rules := 0
+		for i, u := range uids {
+			if o := s.lookup(sess, u); o != nil && o.str("type") == rt.rule {
+				rules++
+				if rules == n {
+					return i, nil
+				}
+			}
+		}
+		if n == rules+1 {
+			return len(uids), nil
+		}
+		return 0, invalid
+	case string:
+		switch p {
+		case "top":
+			return 0, nil
+		case "bottom":
+			return len(uids), nil
+		}
+	case map[string]interface{}:
+		for k, v := range p {
+			id, _ := v.(string)
+			i, end, e := index(id)
+			if e != nil {
+				return 0, e
+			}
+			isSection := s.lookup(sess, uids[i]).str("type") == rt.section
+			switch {
+			case k == "above":
+				return i, nil
+			case k == "below":
+				return end, nil
+			case k == "top" && isSection:
+				return i + 1, nil
+			case k == "bottom" && isSection:
+				return end, nil
+			}
+		}
+	}
+	return 0, invalid
+}
+
+//showRulebase returns a page of the rules of a rulebase, within
+//their sections. The container is named by its parameter, e.g.
+//package, or by uid or name. With use-object-dictionary, the default, rules
+//reference objects by uid and the objects are returned in the
+//objects-dictionary.
+func (s *Server) showRulebase(sess *session, rt rulebaseType, req request) (interface{}, *Error) {
+	var c object
+	var e *Error
+	if _, ok := req[rt.param]; ok {
+		c, e = s.container(sess, rt, req)
+	} else {
+		c, e = s.find(sess, rt.container, req)
+	}
+	if e != nil {
+		return nil, e
+	}
+	limit, offset, e := paging(req)
+	if e != nil {
+		return nil, e
+	}
+	filter := strings.ToLower(req.str("filter"))
+	dictionary := true
+	if b, ok := req["use-object-dictionary"].(bool); ok {
+		dictionary = b
+	}
+
+	var (
+		entries []interface{}
+		section map[string]interface{}
+		//inSection is the number of rules of the section
+		//matching the filter, on the page or not
+		inSection int
+		matched   int
+		number    int
+		included  int
+		refs      []string
+	)
+	onPage := func() bool {
+		return matched >= offset && matched < offset+limit
+	}
+	//flush adds the current section to the entries if it has
+	//rules on the page, or is empty and on the page
+	flush := func(last bool) {
+		if section == nil {
+			return
+		}
+		rules, _ := section["rulebase"].([]interface{})
+		empty := inSection == 0 && len(filter) == 0 &&
+			(onPage() || (last && matched >= offset && matched <= offset+limit))
+		if len(rules) > 0 || empty {
+			entries = append(entries, section)
+		}
+		section = nil
+	}
+	for _, u := range order(c) {
+		o := s.lookup(sess, u)
+		if o == nil {
+			continue
+		}
+		if o.str("type") == rt.section {
+			flush(false)
+			section = s.render(sess, o)
+			section["rulebase"] = []interface{}{}
+			inSection = 0
+			continue
+		}
+		number++
+		if len(filter) > 0 && !o.matches(filter) && !s.refMatches(sess, o, filter) {
+			continue
+		}
+		if onPage() {
+			var r object
+			if dictionary {
+				r = o.copy()
+				for k := range r {
+					if strings.HasPrefix(k, "_") {
+						delete(r, k)
+					}
+				}
+				refs = append(refs, refUIDs(r)...)
+			} else {
+				r = s.render(sess, o)
+			}
+			r["rule-number"] = number
+			included++
+			if section != nil {
+				section["rulebase"] = append(section["rulebase"].([]interface{}), r)
+				if _, ok := section["from"]; !ok {
+					section["from"] = number
+				}
+				section["to"] = number
+			} else {
+				entries = append(entries, r)
+			}
+		}
+		matched++
+		inSection++
+	}
+	flush(true)
+
+	out := map[string]interface{}{
+		"uid":      c.str("uid"),
+		"name":     c.str("name"),
+		"from":     0,
+		"to":       0,
+		"total":    matched,
+		"rulebase": entries,
+	}
+	if entries == nil {
+		out["rulebase"] = []interface{}{}
+	}
+	if included > 0 {
+		out["from"] = offset + 1
+		out["to"] = offset + included
+	}
+	if dictionary {
+		dict := []interface{}{}
+		seen := map[string]bool{}
+		for _, u := range refs {
+			if o := s.lookup(sess, u); o != nil && !seen[u] {
+				seen[u] = true
+				dict = append(dict, s.render(sess, o))
+			}
+		}
+		out["objects-dictionary"] = dict
+	}
+	return out, nil
+}
+
+//refUIDs returns the uids referenced by a rule
+func refUIDs(o object) []string {
+	var out []string
+	add := func(v interface{}) {
+		switch r := v.(type) {
+		case string:
+			out = append(out, r)
+		case []interface{}:
+			for _, u := range r {
+				if uid, ok := u.(string); ok {
+					out = append(out, uid)
+				}
+			}
+		}
+	}
+	for k, v := range o {
+		if refFields[k] {
+			add(v)
+		}
+	}
+	if t, ok := o["track"].(map[string]interface{}); ok {
+		add(t["type"])
+	}
+	return out
+}
+
+//refMatches reports whether the name of an object referenced by a
+//rule contains the lower case filter
+func (s *Server) refMatches(sess *session, o object, filter string) bool {
+	for _, u := range refUIDs(o) {
+		if r := s.lookup(sess, u); r != nil && r.matches(filter) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,580 @@
+//Package fake provides an in-process fake of the Check Point
+//Management API for testing the client, and code built on it,
+//without a management server.
+//
+//The fake keeps objects in memory. Changes made in a session are
+//pending, visible only to that session, until published. Errors
+//are returned with the status codes and payloads of the real
+//service.
+//
+//  srv := fake.NewServer("admin", "vpn12345")
+//  defer srv.Close()
+//  conf := checkptclient.NewAPIConfig(srv.BaseURL(), "admin", "vpn12345", "")
+package fake
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+//APIVersion is the api version reported by the fake
+const APIVersion = "1.3"
+
+//Message is an embedded error, warning or blocking error message
+type Message struct {
+	Message string `json:"message"`
+	Session bool   `json:"current_session,omitempty"`
+}
+
+//Error is the error payload returned by the service
+type Error struct {
+	Code     string    `json:"code"`
+	Message  string    `json:"message"`
+	Warnings []Message `json:"warnings,omitempty"`
+	Errors   []Message `json:"errors,omitempty"`
+	Blocking []Message `json:"blocking-errors,omitempty"`
+
+	status int
+}
+
+//apiError creates an Error returned with the http status
+func apiError(status int, code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), status: status}
+}
+
+//errNotFound is the error for an object that does not exist
+func errNotFound(id string) *Error {
+	return apiError(http.StatusNotFound, "generic_err_object_not_found",
+		"Requested object [%s] not found", id)
+}
+
+//errMissing is the error for a missing required parameter
+func errMissing(param string) *Error {
+	return apiError(http.StatusBadRequest, "generic_err_missing_required_parameters",
+		"Missing parameter: [%s]", param)
+}
+
+//errValidation is the error for a request failing validation with
+//errors or, unless ignored, warnings
+func errValidation(errs, warns []Message) *Error {
+	var parts []string
+	if n := len(errs); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d error%s", n, plural(n)))
+	}
+	if n := len(warns); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d warning%s", n, plural(n)))
+	}
+	e := apiError(http.StatusBadRequest, "err_validation_failed",
+		"Validation failed with %s", strings.Join(parts, " and "))
+	e.Errors = errs
+	e.Warnings = warns
+	return e
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+//object is a stored object, as returned by the show commands
+type object map[string]interface{}
+
+//copy returns a deep copy of the object
+func (o object) copy() object {
+	data, _ := json.Marshal(o)
+	var c object
+	json.Unmarshal(data, &c)
+	return c
+}
+
+func (o object) str(key string) string {
+	s, _ := o[key].(string)
+	return s
+}
+
+//matches reports whether the name, comments or an address of the
+//object contains the lower case filter, ignoring case
+func (o object) matches(filter string) bool {
+	for k, v := range o {
+		if k != "name" && k != "comments" && !strings.Contains(k, "address") && !strings.Contains(k, "subnet") {
+			continue
+		}
+		if s, ok := v.(string); ok && strings.Contains(strings.ToLower(s), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+//session is a management session. A session outlives its sid so
+//that a login with continue-last-session resumes pending changes.
+type session struct {
+	uid         string
+	user        string
+	name        string
+	description string
+	timeout     time.Duration
+	//changes are the pending changes by object uid, a nil
+	//object is a pending delete
+	changes map[string]object
+	//srv is the server of the session's domain
+	srv *Server
+	//readOnly sessions may not make changes
+	readOnly bool
+}
+
+//sid is a session id handed out by login
+type sid struct {
+	s       *session
+	expires time.Time
+}
+
+//command handles a command for a session and returns the response
+type command func(s *session, req request) (interface{}, *Error)
+
+//Server is a fake Check Point Management API server. It is safe for
+//concurrent use.
+//
+//A Server is also a Multi-Domain Server: its own objects are those
+//of the global domain, and each domain added has a Server with its
+//own objects and sessions. The servers of the domains share the
+//listener, users and session ids of the Server.
+type Server struct {
+	*httptest.Server
+
+	//name and uid are those of the domain, empty for the global
+	//domain
+	name string
+	uid  string
+	//mu, users, apiKeys, sids and domains are shared by the
+	//servers of all domains, apiKeys are the users by api key
+	mu      *sync.Mutex
+	users   map[string]string
+	apiKeys map[string]string
+	sids    map[string]*sid
+	domains map[string]*Server
+
+	sessions  map[string]*session
+	open      map[string]*session
+	published map[string]object
+	tasks     map[string]*task
+	commands  map[string]command
+	failures  map[string][]*Error
+	//taskPolls is the number of show-task requests new tasks
+	//are in progress for, taskFailures the queued failures
+	taskPolls    int
+	taskFailures []string
+	//gatewayFailures are the failures of policy installation by
+	//gateway name, installed the packages installed on them
+	gatewayFailures map[string]string
+	installed       map[string]string
+}
+
+//NewServer starts a Server accepting logins from user with pass.
+//The caller should call Close when finished.
+func NewServer(user, pass string) *Server {
+	s := newServer("", nil)
+	s.users[user] = pass
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+//newServer returns the server of a domain with the predefined
+//objects, sharing the users, session ids and domains of root, the
+//server of the global domain, or the global domain when root is nil
+func newServer(name string, root *Server) *Server {
+	if root == nil {
+		root = &Server{
+			mu:      &sync.Mutex{},
+			users:   make(map[string]string),
+			apiKeys: make(map[string]string),
+			sids:    make(map[string]*sid),
+			domains: make(map[string]*Server),
+		}
+	}
+	s := &Server{
+		name:      name,
+		mu:        root.mu,
+		users:     root.users,
+		apiKeys:   root.apiKeys,
+		sids:      root.sids,
+		domains:   root.domains,
+		sessions:  make(map[string]*session),
+		open:      make(map[string]*session),
+		published: make(map[string]object),
+		tasks:     make(map[string]*task),
+		failures:  make(map[string][]*Error),
+
+		gatewayFailures: make(map[string]string),
+		installed:       make(map[string]string),
+	}
+	s.commands = map[string]command{
+		"logout":    s.logout,
+		"publish":   s.publish,
+		"discard":   s.discard,
+		"show-task": s.showTask,
+
+		"keepalive":         s.keepalive,
+		"show-session":      s.showSession,
+		"show-sessions":     s.showSessions,
+		"switch-session":    s.switchSession,
+		"take-over-session": s.takeOverSession,
+
+		"show-domains":    s.showDomains,
+		"login-to-domain": s.loginToDomain,
+
+		"install-policy": s.installPolicy,
+		"verify-policy":  s.verifyPolicy,
+
+		"show-gateways-and-servers": func(sess *session, req request) (interface{}, *Error) {
+			return s.listObjects(sess, "simple-gateway", req)
+		},
+	}
+	for _, o := range builtins() {
+		s.published[o.str("uid")] = o
+	}
+	s.objectCommands("host", "hosts", validateHost)
+	s.objectCommands("network", "networks", validateNetwork)
+	s.objectCommands("address-range", "address-ranges", validateRange)
+	s.objectCommands("group", "groups", groupValidator("host", "network", "address-range", "group"))
+	s.objectCommands("service-tcp", "services-tcp", serviceValidator(3600))
+	s.objectCommands("service-udp", "services-udp", serviceValidator(40))
+	s.objectCommands("service-icmp", "services-icmp", validateICMP)
+	s.objectCommands("service-group", "service-groups",
+		groupValidator("service-tcp", "service-udp", "service-icmp", "service-group"))
+	s.accessCommands()
+	s.natCommands()
+	return s
+}
+
+//BaseURL returns the url of the web api for use as the client's
+//base url
+func (s *Server) BaseURL() string {
+	return s.URL + "/web_api/v" + APIVersion
+}
+
+//AddUser adds a user who may log in
+func (s *Server) AddUser(user, pass string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[user] = pass
+}
+
+//AddAPIKey adds an api key logging in as user, who need not have a
+//password
+func (s *Server) AddAPIKey(key, user string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKeys[key] = user
+}
+
+//Fail makes the next call of command fail with status and the error
+//payload e, e.g. to test handling of blocking errors. Repeated calls
+//queue further failures.
+func (s *Server) Fail(command string, status int, e Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.status = status
+	s.failures[command] = append(s.failures[command], &e)
+}
+
+//ExpireSessions invalidates all session ids as if they had timed out
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.sids {
+		delete(s.sids, id)
+	}
+}
+
+//Published returns a copy of the published object of type typ with
+//name, e.g. Published("host", "web1")
+func (s *Server) Published(typ, name string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.published {
+		if o.str("type") == typ && o.str("name") == name {
+			return o.copy(), true
+		}
+	}
+	return nil, false
+}
+
+//commandPath matches /web_api/<command> with an optional version
+var commandPath = regexp.MustCompile(`^/web_api/(?:v[0-9.]+/)?([a-z0-9-]+)$`)
+
+//request is a command's json payload
+type request map[string]interface{}
+
+func (r request) str(key string) string {
+	s, _ := r[key].(string)
+	return s
+}
+
+func (r request) flag(key string) bool {
+	b, _ := r[key].(bool)
+	return b
+}
+
+func (r request) num(key string, def int) int {
+	if f, ok := r[key].(float64); ok {
+		return int(f)
+	}
+	return def
+}
+
+//serve routes a request to its command, checking the session id for
+//all commands but login
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	m := commandPath.FindStringSubmatch(r.URL.Path)
+	if m == nil || r.Method != http.MethodPost {
+		writeJSON(w, http.StatusNotFound, apiError(http.StatusNotFound, "generic_err_command_not_found",
+			"Unknown command \"%s\"", strings.TrimPrefix(r.URL.Path, "/web_api/")))
+		return
+	}
+	name := m[1]
+
+	req := request{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err == nil && len(data) > 0 {
+		err = json.Unmarshal(data, &req)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError(http.StatusBadRequest, "generic_err_invalid_syntax",
+			"Invalid json in request body"))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f := s.failures[name]; len(f) > 0 {
+		s.failures[name] = f[1:]
+		writeJSON(w, f[0].status, f[0])
+		return
+	}
+
+	var (
+		resp interface{}
+		e    *Error
+	)
+	switch {
+	case name == "login":
+		resp, e = s.login(req)
+	case name == "login-as-root":
+		resp, e = s.loginAsRoot(req)
+	default:
+		//commands run on the server of the session's domain
+		if _, ok := s.commands[name]; !ok {
+			e = apiError(http.StatusNotFound, "generic_err_command_not_found", "Unknown command \"%s\"", name)
+		} else if sess, se := s.session(r.Header.Get("X-chkp-sid")); se != nil {
+			e = se
+		} else if sess.readOnly && changes(name) {
+			e = apiError(http.StatusForbidden, "generic_err_read_only",
+				"Command [%s] is not allowed in a read only session", name)
+		} else {
+			resp, e = sess.srv.commands[name](sess, req)
+		}
+	}
+	if e != nil {
+		writeJSON(w, e.status, e)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+//session validates a session id, extending its expiry
+func (s *Server) session(id string) (*session, *Error) {
+	if len(id) == 0 {
+		return nil, apiError(http.StatusUnauthorized, "generic_err_missing_session_id",
+			"Missing header: [X-chkp-sid]")
+	}
+	sd, ok := s.sids[id]
+	if !ok || time.Now().After(sd.expires) {
+		delete(s.sids, id)
+		return nil, apiError(http.StatusBadRequest, "generic_err_wrong_session_id",
+			"Wrong session id [%s]. Session may be expired. Please check session id and resend the request", id)
+	}
+	sd.expires = time.Now().Add(sd.s.timeout)
+	return sd.s, nil
+}
+
+//login starts a session, or resumes the user's last session when
+//continue-last-session is set, in the global domain or the domain
+//requested
+func (s *Server) login(req request) (interface{}, *Error) {
+	user := req.str("user")
+	if key := req.str("api-key"); len(key) > 0 {
+		var ok bool
+		if user, ok = s.apiKeys[key]; !ok {
+			return nil, apiError(http.StatusBadRequest, "err_login_failed", "Authentication to server failed.")
+		}
+	} else if len(user) == 0 {
+		return nil, errMissing("user")
+	} else if pass, ok := s.users[user]; !ok || pass != req.str("password") {
+		return nil, apiError(http.StatusBadRequest, "err_login_failed", "Authentication to server failed.")
+	}
+	return s.loginAs(user, req)
+}
+
+//loginAsRoot starts a session of the local administrator, root,
+//without credentials as login-as-root does on the management server
+func (s *Server) loginAsRoot(req request) (interface{}, *Error) {
+	return s.loginAs("root", req)
+}
+
+//loginAs starts a session of user, authenticated already, in the
+//global domain or the domain requested
+func (s *Server) loginAs(user string, req request) (interface{}, *Error) {
+	timeout := req.num("session-timeout", 600)
+	if timeout < 10 || timeout > 3600 {
+		return nil, apiError(http.StatusBadRequest, "generic_err_invalid_parameter",
+			"Invalid parameter for [session-timeout]. The value must be between 10 and 3600")
+	}
+	d := s
+	if name := req.str("domain"); len(name) > 0 {
+		var e *Error
+		if d, e = s.domain(name); e != nil {
+			return nil, e
+		}
+	}
+	return d.start(user, timeout, req), nil
+}
+
+//start starts a session of user, or resumes the user's last session
+//when continue-last-session is set, and returns the login response
+func (s *Server) start(user string, timeout int, req request) map[string]interface{} {
+	sess, ok := s.sessions[user]
+	if !ok || !req.flag("continue-last-session") {
+		sess = &session{
+			uid:         newUID(),
+			user:        user,
+			name:        req.str("session-name"),
+			description: req.str("session-description"),
+			changes:     make(map[string]object),
+			srv:         s,
+		}
+		s.sessions[user] = sess
+		s.open[sess.uid] = sess
+	}
+	sess.timeout = time.Duration(timeout) * time.Second
+	sess.readOnly = req.flag("read-only")
+
+	id := newSID()
+	s.sids[id] = &sid{s: sess, expires: time.Now().Add(sess.timeout)}
+	now := time.Now()
+	resp := map[string]interface{}{
+		"uid":             sess.uid,
+		"sid":             id,
+		"url":             s.URL + "/web_api",
+		"session-timeout": timeout,
+		"last-login-was-at": map[string]interface{}{
+			"posix":    now.UnixNano() / int64(time.Millisecond),
+			"iso-8601": now.Format("2006-01-02T15:04-0700"),
+		},
+		"read-only":          sess.readOnly,
+		"api-server-version": APIVersion,
+	}
+	if len(s.name) > 0 {
+		resp["domain"] = map[string]interface{}{"name": s.name, "domain-type": "domain"}
+	}
+	return resp
+}
+
+//logout ends the session id. Pending changes are kept for a later
+//login continuing the session, or for another session taking the
+//session over, a session without changes is closed.
+func (s *Server) logout(sess *session, req request) (interface{}, *Error) {
+	s.disconnect(sess)
+	if len(sess.changes) == 0 {
+		delete(s.open, sess.uid)
+	}
+	return map[string]string{"message": "OK"}, nil
+}
+
+//publish makes the session's pending changes visible to all, or
+//keeps them pending when a task failure is queued
+func (s *Server) publish(sess *session, req request) (interface{}, *Error) {
+	if msg, ok := s.taskFailure(); ok {
+		id := s.newTask("Publish operation", "failed", []interface{}{
+			map[string]interface{}{"statusCode": "failed", "statusDescription": msg},
+		})
+		return map[string]string{"task-id": id}, nil
+	}
+	for uid, o := range sess.changes {
+		if o == nil {
+			delete(s.published, uid)
+			continue
+		}
+		meta, _ := o["meta-info"].(map[string]interface{})
+		if meta != nil {
+			meta["lock"] = "unlocked"
+		}
+		s.published[uid] = o
+	}
+	n := len(sess.changes)
+	sess.changes = make(map[string]object)
+
+	id := s.newTask("Publish operation", "succeeded", []interface{}{
+		map[string]interface{}{
+			"publishResponse": map[string]interface{}{
+				"numberOfPublishedChanges": n,
+				"mode":                     "async",
+			},
+		},
+	})
+	return map[string]string{"task-id": id}, nil
+}
+
+//discard drops the session's pending changes
+func (s *Server) discard(sess *session, req request) (interface{}, *Error) {
+	n := len(sess.changes)
+	sess.changes = make(map[string]object)
+	return map[string]interface{}{
+		"message":                     "OK",
+		"number-of-discarded-changes": n,
+	}, nil
+}
+
+//changes reports whether the command makes changes, and so is not
+//allowed in a read only session
+func changes(command string) bool {
+	for _, p := range []string{"add-", "set-", "delete-", "publish", "discard", "install-", "take-over-"} {
+		if strings.HasPrefix(command, p) {
+			return true
+		}
+	}
+	return false
+}
+
+//writeJSON writes v as the json response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+//newUID returns a random uuid formatted object uid
+func newUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+//newSID returns a random session id
+func newSID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)[:43]
+}
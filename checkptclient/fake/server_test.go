@@ -0,0 +1,138 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+//call posts a command and decodes the response into a map
+func call(t *testing.T, srv *Server, sid, command string, payload interface{}) (int, map[string]interface{}) {
+	data, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", srv.BaseURL()+"/"+command, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if len(sid) > 0 {
+		req.Header.Set("X-chkp-sid", sid)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	out := map[string]interface{}{}
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+func login(t *testing.T, srv *Server) string {
+	code, out := call(t, srv, "", "login", map[string]string{"user": "admin", "password": "vpn12345"})
+	if code != 200 {
+		t.Fatalf("Login failed: %d %v", code, out)
+	}
+	return out["sid"].(string)
+}
+
+func TestLogin(t *testing.T) {
+	srv := NewServer("admin", "vpn12345")
+	defer srv.Close()
+
+	code, out := call(t, srv, "", "login", map[string]string{"user": "admin", "password": "wrong"})
+	if code != 400 || out["code"] != "err_login_failed" {
+		t.Fatalf("Expected login failure, got: %d %v", code, out)
+	}
+	sid := login(t, srv)
+
+	code, out = call(t, srv, "", "show-host", map[string]string{"name": "h1"})
+	if code != 401 || out["code"] != "generic_err_missing_session_id" {
+		t.Fatalf("Expected missing sid, got: %d %v", code, out)
+	}
+	if code, _ = call(t, srv, sid, "logout", struct{}{}); code != 200 {
+		t.Fatalf("Logout failed: %d", code)
+	}
+	code, out = call(t, srv, sid, "show-host", map[string]string{"name": "h1"})
+	if code != 400 || out["code"] != "generic_err_wrong_session_id" {
+		t.Fatalf("Expected wrong sid, got: %d %v", code, out)
+	}
+}
+
+func TestPendingAndPublished(t *testing.T) {
+	srv := NewServer("admin", "vpn12345")
+	defer srv.Close()
+	srv.AddUser("bob", "xxxxx")
+
+	sid := login(t, srv)
+	code, out := call(t, srv, "", "login", map[string]string{"user": "bob", "password": "xxxxx"})
+	if code != 200 {
+		t.Fatalf("Login failed: %d %v", code, out)
+	}
+	other := out["sid"].(string)
+
+	host := map[string]string{"name": "h1", "ipv4-address": "10.0.0.1"}
+	if code, out = call(t, srv, sid, "add-host", host); code != 200 || out["type"] != "host" {
+		t.Fatalf("Add host failed: %d %v", code, out)
+	}
+	//pending changes are only seen by the session
+	if code, _ = call(t, srv, other, "show-host", map[string]string{"name": "h1"}); code != 404 {
+		t.Fatalf("Expected pending host to be hidden, got: %d", code)
+	}
+	if _, ok := srv.Published("host", "h1"); ok {
+		t.Fatal("Expected host not to be published")
+	}
+
+	code, out = call(t, srv, sid, "publish", struct{}{})
+	if code != 200 {
+		t.Fatalf("Publish failed: %d %v", code, out)
+	}
+	code, out = call(t, srv, sid, "show-task", map[string]interface{}{"task-id": out["task-id"]})
+	if code != 200 || out["tasks"].([]interface{})[0].(map[string]interface{})["status"] != "succeeded" {
+		t.Fatalf("Unexpected task: %d %v", code, out)
+	}
+	if code, _ = call(t, srv, other, "show-host", map[string]string{"name": "h1"}); code != 200 {
+		t.Fatalf("Expected published host, got: %d", code)
+	}
+
+	//discarded changes are dropped
+	call(t, srv, sid, "set-host", map[string]string{"name": "h1", "new-name": "h2"})
+	code, out = call(t, srv, sid, "discard", struct{}{})
+	if code != 200 || out["number-of-discarded-changes"].(float64) != 1 {
+		t.Fatalf("Unexpected discard: %d %v", code, out)
+	}
+	if code, _ = call(t, srv, sid, "show-host", map[string]string{"name": "h1"}); code != 200 {
+		t.Fatalf("Expected discarded rename, got: %d", code)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	srv := NewServer("admin", "vpn12345")
+	defer srv.Close()
+	sid := login(t, srv)
+
+	call(t, srv, sid, "add-host", map[string]string{"name": "h1", "ipv4-address": "10.0.0.1"})
+	code, out := call(t, srv, sid, "add-host", map[string]string{"name": "h1", "ipv4-address": "10.0.0.2"})
+	if code != 400 || out["code"] != "err_validation_failed" || len(out["errors"].([]interface{})) != 1 {
+		t.Fatalf("Expected duplicate name error, got: %d %v", code, out)
+	}
+	code, out = call(t, srv, sid, "add-host", map[string]string{"name": "h2", "ipv4-address": "10.0.0.1"})
+	if code != 400 || len(out["warnings"].([]interface{})) != 1 {
+		t.Fatalf("Expected duplicate ip warning, got: %d %v", code, out)
+	}
+	code, _ = call(t, srv, sid, "add-host", map[string]interface{}{"name": "h2", "ipv4-address": "10.0.0.1", "ignore-warnings": true})
+	if code != 200 {
+		t.Fatalf("Expected warning to be ignored, got: %d", code)
+	}
+	code, out = call(t, srv, sid, "delete-host", map[string]string{"uid": "nope"})
+	if code != 404 || out["code"] != "generic_err_object_not_found" {
+		t.Fatalf("Expected not found, got: %d %v", code, out)
+	}
+
+	srv.Fail("publish", 409, Error{
+		Code:     "err_publish_failed",
+		Message:  "Publish failed",
+		Blocking: []Message{{Message: "Object is locked"}},
+	})
+	code, out = call(t, srv, sid, "publish", struct{}{})
+	if code != 409 || len(out["blocking-errors"].([]interface{})) != 1 {
+		t.Fatalf("Expected injected failure, got: %d %v", code, out)
+	}
+}
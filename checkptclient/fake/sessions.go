@@ -0,0 +1,167 @@
+package fake
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//keepalive keeps the session alive, the session id's expiry is
+//extended by any command
+func (s *Server) keepalive(sess *session, req request) (interface{}, *Error) {
+	return map[string]string{"message": "OK"}, nil
+}
+
+//connected reports whether a session id is in use for the session
+func (s *Server) connected(sess *session) bool {
+	for _, sd := range s.sids {
+		if sd.s == sess {
+			return true
+		}
+	}
+	return false
+}
+
+//disconnect ends the session ids in use for the session
+func (s *Server) disconnect(sess *session) {
+	for id, sd := range s.sids {
+		if sd.s == sess {
+			delete(s.sids, id)
+		}
+	}
+}
+
+//sessionObject renders a session in the format of show-session
+func (s *Server) sessionObject(sess *session) object {
+	locks := 0
+	for _, o := range sess.changes {
+		if o != nil {
+			locks++
+		}
+	}
+	return object{
+		"uid":             sess.uid,
+		"name":            sess.name,
+		"type":            "session",
+		"user-name":       sess.user,
+		"description":     sess.description,
+		"application":     "WEB_API",
+		"state":           "open",
+		"connection-mode": "read write",
+		"changes":         len(sess.changes),
+		"locks":           locks,
+		"in-work":         len(sess.changes) > 0,
+		"expired-session": !s.connected(sess),
+		"session-timeout": int(sess.timeout.Seconds()),
+	}
+}
+
+//target returns the open session with the uid of a request
+func (s *Server) target(req request) (*session, *Error) {
+	uid := req.str("uid")
+	if len(uid) == 0 {
+		return nil, errMissing("uid")
+	}
+	t, ok := s.open[uid]
+	if !ok {
+		return nil, errNotFound(uid)
+	}
+	return t, nil
+}
+
+//showSession returns the session with uid, or the current session
+func (s *Server) showSession(sess *session, req request) (interface{}, *Error) {
+	if len(req.str("uid")) == 0 {
+		return s.sessionObject(sess), nil
+	}
+	t, e := s.target(req)
+	if e != nil {
+		return nil, e
+	}
+	return s.sessionObject(t), nil
+}
+
+//showSessions returns a page of the open sessions ordered by user
+//and uid so that pages are stable
+func (s *Server) showSessions(sess *session, req request) (interface{}, *Error) {
+	limit, offset, e := paging(req)
+	if e != nil {
+		return nil, e
+	}
+	filter := strings.ToLower(req.str("filter"))
+	var objs []object
+	for _, o := range s.open {
+		so := s.sessionObject(o)
+		if len(filter) == 0 || so.matches(filter) || strings.Contains(strings.ToLower(o.user), filter) {
+			objs = append(objs, so)
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		if objs[i].str("user-name") != objs[j].str("user-name") {
+			return objs[i].str("user-name") < objs[j].str("user-name")
+		}
+		return objs[i].str("uid") < objs[j].str("uid")
+	})
+	return page(objs, offset, limit), nil
+}
+
+//attach moves the session ids of sess to the session t. sess is
+//closed unless it has pending changes.
+func (s *Server) attach(sess, t *session) {
+	for _, sd := range s.sids {
+		if sd.s == sess {
+			sd.s = t
+		}
+	}
+	if len(sess.changes) == 0 && sess != t {
+		delete(s.open, sess.uid)
+	}
+	t.timeout = sess.timeout
+	s.sessions[t.user] = t
+}
+
+//switchSession switches to another session of the user that is not
+//in use
+func (s *Server) switchSession(sess *session, req request) (interface{}, *Error) {
+	t, e := s.target(req)
+	if e != nil {
+		return nil, e
+	}
+	if t.user != sess.user {
+		return nil, apiError(http.StatusBadRequest, "generic_err_invalid_parameter",
+			"Session [%s] belongs to another user, use take-over-session", t.uid)
+	}
+	if t != sess && s.connected(t) {
+		return nil, apiError(http.StatusBadRequest, "generic_err_invalid_parameter",
+			"Session [%s] is in use", t.uid)
+	}
+	s.attach(sess, t)
+	return s.sessionObject(t), nil
+}
+
+//takeOverSession takes over a session of any user and switches to
+//it. A session in use is only taken over when
+//disconnect-active-session is set.
+func (s *Server) takeOverSession(sess *session, req request) (interface{}, *Error) {
+	t, e := s.target(req)
+	if e != nil {
+		return nil, e
+	}
+	if t == sess {
+		return nil, apiError(http.StatusBadRequest, "generic_err_invalid_parameter",
+			"Cannot take over the current session")
+	}
+	if s.connected(t) {
+		if !req.flag("disconnect-active-session") {
+			return nil, apiError(http.StatusBadRequest, "generic_err_invalid_parameter",
+				"Session [%s] is in use, set disconnect-active-session to take it over", t.uid)
+		}
+		s.disconnect(t)
+	}
+	if s.sessions[t.user] == t {
+		delete(s.sessions, t.user)
+	}
+	t.user = sess.user
+	s.attach(sess, t)
+	return s.sessionObject(t), nil
+}
@@ -0,0 +1,96 @@
+package fake
+
+//task is an asynchronous task. It is in progress for a number of
+//show-task requests, then completes with its final state.
+type task struct {
+	final object
+	polls int
+	total int
+}
+
+//SetTaskPolls makes new tasks, e.g. of publish, report being in
+//progress for the first polls show-task requests for them
+func (s *Server) SetTaskPolls(polls int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.taskPolls = polls
+}
+
+//FailTask queues a failure for the next task, which fails with the
+//status description msg. A failed publish leaves the changes
+//pending.
+func (s *Server) FailTask(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.taskFailures = append(s.taskFailures, msg)
+}
+
+//taskFailure returns the next queued task failure
+func (s *Server) taskFailure() (string, bool) {
+	if len(s.taskFailures) == 0 {
+		return "", false
+	}
+	msg := s.taskFailures[0]
+	s.taskFailures = s.taskFailures[1:]
+	return msg, true
+}
+
+//newTask stores a task completing with status and details and
+//returns its id
+func (s *Server) newTask(name, status string, details []interface{}) string {
+	id := newUID()
+	s.tasks[id] = &task{
+		final: object{
+			"task-id":             id,
+			"task-name":           name,
+			"status":              status,
+			"progress-percentage": 100,
+			"suppressed":          false,
+			"task-details":        details,
+		},
+		polls: s.taskPolls,
+		total: s.taskPolls,
+	}
+	return id
+}
+
+//state returns the task as reported by show-task, counting the
+//request
+func (t *task) state() object {
+	if t.polls <= 0 {
+		return t.final.copy()
+	}
+	t.polls--
+	o := t.final.copy()
+	o["status"] = "in progress"
+	o["progress-percentage"] = 100 * (t.total - t.polls - 1) / t.total
+	o["task-details"] = []interface{}{}
+	return o
+}
+
+//showTask returns the tasks for one or a list of task ids
+func (s *Server) showTask(sess *session, req request) (interface{}, *Error) {
+	var ids []string
+	switch v := req["task-id"].(type) {
+	case string:
+		ids = []string{v}
+	case []interface{}:
+		for _, id := range v {
+			if s, ok := id.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errMissing("task-id")
+	}
+	var tasks []object
+	for _, id := range ids {
+		t, ok := s.tasks[id]
+		if !ok {
+			return nil, errNotFound(id)
+		}
+		tasks = append(tasks, t.state())
+	}
+	return map[string]interface{}{"tasks": tasks}, nil
+}
@@ -0,0 +1,75 @@
+package checkptclient
+
+import "context"
+
+const (
+	endpointAddNatRule       = `add-nat-rule`
+	endpointShowNatRule      = `show-nat-rule`
+	endpointSetNatRule       = `set-nat-rule`
+	endpointDeleteNatRule    = `delete-nat-rule`
+	endpointShowNatRulebase  = `show-nat-rulebase`
+	endpointAddNatSection    = `add-nat-section`
+	endpointShowNatSection   = `show-nat-section`
+	endpointSetNatSection    = `set-nat-section`
+	endpointDeleteNatSection = `delete-nat-section`
+)
+
+//CreateNatRule adds a manual NAT rule to the rule's Package at the
+//rule's Position, which is required
+func (a *APIClient) CreateNatRule(ctx context.Context, rule NatRule) (NatRule, error) {
+	return command[NatRule, NatRule](ctx, a, endpointAddNatRule, rule)
+}
+
+//ShowNatRule returns a NAT rule of pkg by uid or name
+func (a *APIClient) ShowNatRule(ctx context.Context, pkg string, id ObjectID) (NatRule, error) {
+	return command[natRuleRequest, NatRule](ctx, a, endpointShowNatRule, natRuleRequest{ObjectID: id, Package: pkg})
+}
+
+//SetNatRule changes the NAT rule of the rule's Package identified by
+//its UID, Name or RuleNumber. NewPosition moves the rule.
+func (a *APIClient) SetNatRule(ctx context.Context, rule NatRule) (NatRule, error) {
+	return command[NatRule, NatRule](ctx, a, endpointSetNatRule, rule)
+}
+
+//DeleteNatRule deletes a NAT rule of pkg by uid or name
+func (a *APIClient) DeleteNatRule(ctx context.Context, pkg string, id ObjectID) error {
+	_, err := command[natRuleRequest, NoMessage](ctx, a, endpointDeleteNatRule, natRuleRequest{ObjectID: id, Package: pkg})
+	return err
+}
+
+//ShowNatRulebase returns a page of the NAT rules of pkg, with the
+//sections containing them. The references of the rules are resolved
+//to objects.
+func (a *APIClient) ShowNatRulebase(ctx context.Context, pkg string, opts RulebaseOptions) (NatRulebase, error) {
+	rb, err := command[natRulebaseRequest, NatRulebase](ctx, a, endpointShowNatRulebase,
+		natRulebaseRequest{Package: pkg, RulebaseOptions: opts, UseObjectDictionary: true})
+	if err != nil {
+		return rb, err
+	}
+	rb.dereference()
+	return rb, nil
+}
+
+//CreateNatSection adds a section to the section's Package at the
+//section's Position, which is required
+func (a *APIClient) CreateNatSection(ctx context.Context, section NatSection) (NatSection, error) {
+	return command[NatSection, NatSection](ctx, a, endpointAddNatSection, section)
+}
+
+//ShowNatSection returns a NAT section of pkg by uid or name
+func (a *APIClient) ShowNatSection(ctx context.Context, pkg string, id ObjectID) (NatSection, error) {
+	return command[natRuleRequest, NatSection](ctx, a, endpointShowNatSection, natRuleRequest{ObjectID: id, Package: pkg})
+}
+
+//SetNatSection changes the NAT section of the section's Package
+//identified by its UID or Name
+func (a *APIClient) SetNatSection(ctx context.Context, section NatSection) (NatSection, error) {
+	return command[NatSection, NatSection](ctx, a, endpointSetNatSection, section)
+}
+
+//DeleteNatSection deletes a NAT section of pkg by uid or name. The
+//rules of the section join the section above.
+func (a *APIClient) DeleteNatSection(ctx context.Context, pkg string, id ObjectID) error {
+	_, err := command[natRuleRequest, NoMessage](ctx, a, endpointDeleteNatSection, natRuleRequest{ObjectID: id, Package: pkg})
+	return err
+}
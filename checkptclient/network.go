@@ -0,0 +1,121 @@
+package checkptclient
+
+import "context"
+
+const (
+	endpointAddNetwork         = `add-network`
+	endpointShowNetwork        = `show-network`
+	endpointShowNetworks       = `show-networks`
+	endpointSetNetwork         = `set-network`
+	endpointDeleteNetwork      = `delete-network`
+	endpointAddAddressRange    = `add-address-range`
+	endpointShowAddressRange   = `show-address-range`
+	endpointShowAddressRanges  = `show-address-ranges`
+	endpointSetAddressRange    = `set-address-range`
+	endpointDeleteAddressRange = `delete-address-range`
+	endpointAddGroup           = `add-group`
+	endpointShowGroup          = `show-group`
+	endpointShowGroups         = `show-groups`
+	endpointSetGroup           = `set-group`
+	endpointDeleteGroup        = `delete-group`
+)
+
+//CreateNetwork creates a Network on the CheckPoint service
+func (a *APIClient) CreateNetwork(ctx context.Context, network Network) (Network, error) {
+	return command[Network, Network](ctx, a, endpointAddNetwork, network)
+}
+
+//ShowNetwork returns a Network by uid or name
+func (a *APIClient) ShowNetwork(ctx context.Context, id ObjectID) (Network, error) {
+	return command[showRequest, Network](ctx, a, endpointShowNetwork, showRequest{ObjectID: id})
+}
+
+//ShowNetworks returns a page of the networks matching the options
+func (a *APIClient) ShowNetworks(ctx context.Context, opts ListOptions) (ObjectList[Network], error) {
+	return command[ListOptions, ObjectList[Network]](ctx, a, endpointShowNetworks, opts)
+}
+
+//SetNetwork changes the Network identified by its UID or Name
+func (a *APIClient) SetNetwork(ctx context.Context, network Network) (Network, error) {
+	return command[Network, Network](ctx, a, endpointSetNetwork, network)
+}
+
+//DeleteNetwork deletes a Network by uid or name
+func (a *APIClient) DeleteNetwork(ctx context.Context, id ObjectID, opts DeleteOptions) error {
+	_, err := command[deleteRequest, NoMessage](ctx, a, endpointDeleteNetwork,
+		deleteRequest{ObjectID: id, DeleteOptions: opts})
+	return err
+}
+
+//CreateAddressRange creates an AddressRange on the CheckPoint service
+func (a *APIClient) CreateAddressRange(ctx context.Context, r AddressRange) (AddressRange, error) {
+	return command[AddressRange, AddressRange](ctx, a, endpointAddAddressRange, r)
+}
+
+//ShowAddressRange returns an AddressRange by uid or name
+func (a *APIClient) ShowAddressRange(ctx context.Context, id ObjectID) (AddressRange, error) {
+	return command[showRequest, AddressRange](ctx, a, endpointShowAddressRange, showRequest{ObjectID: id})
+}
+
+//ShowAddressRanges returns a page of the address ranges matching
+//the options
+func (a *APIClient) ShowAddressRanges(ctx context.Context, opts ListOptions) (ObjectList[AddressRange], error) {
+	return command[ListOptions, ObjectList[AddressRange]](ctx, a, endpointShowAddressRanges, opts)
+}
+
+//SetAddressRange changes the AddressRange identified by its UID
+//or Name
+func (a *APIClient) SetAddressRange(ctx context.Context, r AddressRange) (AddressRange, error) {
+	return command[AddressRange, AddressRange](ctx, a, endpointSetAddressRange, r)
+}
+
+//DeleteAddressRange deletes an AddressRange by uid or name
+func (a *APIClient) DeleteAddressRange(ctx context.Context, id ObjectID, opts DeleteOptions) error {
+	_, err := command[deleteRequest, NoMessage](ctx, a, endpointDeleteAddressRange,
+		deleteRequest{ObjectID: id, DeleteOptions: opts})
+	return err
+}
+
+//CreateGroup creates a Group on the CheckPoint service
+func (a *APIClient) CreateGroup(ctx context.Context, group Group) (Group, error) {
+	return command[Group, Group](ctx, a, endpointAddGroup, group)
+}
+
+//ShowGroup returns a Group by uid or name
+func (a *APIClient) ShowGroup(ctx context.Context, id ObjectID) (Group, error) {
+	return command[showRequest, Group](ctx, a, endpointShowGroup, showRequest{ObjectID: id})
+}
+
+//ShowGroups returns a page of the groups matching the options
+func (a *APIClient) ShowGroups(ctx context.Context, opts ListOptions) (ObjectList[Group], error) {
+	return command[ListOptions, ObjectList[Group]](ctx, a, endpointShowGroups, opts)
+}
+
+//SetGroup changes the Group identified by its UID or Name.
+//Members, when set, replace the members of the group.
+func (a *APIClient) SetGroup(ctx context.Context, group Group) (Group, error) {
+	return command[Group, Group](ctx, a, endpointSetGroup, group)
+}
+
+//AddGroupMembers adds members to a group, leaving its other
+//members in place
+func (a *APIClient) AddGroupMembers(ctx context.Context, id ObjectID, members ...Member) (Group, error) {
+	req := membersRequest{ObjectID: id}
+	req.Members.Add = members
+	return command[membersRequest, Group](ctx, a, endpointSetGroup, req)
+}
+
+//RemoveGroupMembers removes members from a group
+func (a *APIClient) RemoveGroupMembers(ctx context.Context, id ObjectID, members ...Member) (Group, error) {
+	req := membersRequest{ObjectID: id}
+	req.Members.Remove = members
+	return command[membersRequest, Group](ctx, a, endpointSetGroup, req)
+}
+
+//DeleteGroup deletes a Group by uid or name. The members of the
+//group are not deleted.
+func (a *APIClient) DeleteGroup(ctx context.Context, id ObjectID, opts DeleteOptions) error {
+	_, err := command[deleteRequest, NoMessage](ctx, a, endpointDeleteGroup,
+		deleteRequest{ObjectID: id, DeleteOptions: opts})
+	return err
+}
@@ -0,0 +1,45 @@
+package checkptclient
+
+import "context"
+
+const (
+	endpointInstallPolicy = `install-policy`
+	endpointVerifyPolicy  = `verify-policy`
+)
+
+//InstallPolicy installs a policy package on gateways and waits for
+//the installation to complete. The task details hold the result per
+//gateway, a *TaskError is returned unless it succeeded on all.
+func (a *APIClient) InstallPolicy(ctx context.Context, req InstallPolicyRequest) (Task, error) {
+	id, err := a.InstallPolicyTask(ctx, req)
+	if err != nil {
+		return Task{}, err
+	}
+	return a.WaitForTask(ctx, id)
+}
+
+//InstallPolicyTask starts installing a policy package on gateways
+//and returns the id of the task, see WaitForTask
+func (a *APIClient) InstallPolicyTask(ctx context.Context, req InstallPolicyRequest) (string, error) {
+	resp, err := command[InstallPolicyRequest, taskResponse](ctx, a, endpointInstallPolicy, req)
+	return resp.TaskID, err
+}
+
+//VerifyPolicy verifies the policy package pkg can be installed and
+//waits for the verification to complete. A *TaskError is returned
+//if it fails.
+func (a *APIClient) VerifyPolicy(ctx context.Context, pkg string) (Task, error) {
+	id, err := a.VerifyPolicyTask(ctx, pkg)
+	if err != nil {
+		return Task{}, err
+	}
+	return a.WaitForTask(ctx, id)
+}
+
+//VerifyPolicyTask starts verifying the policy package pkg and
+//returns the id of the task, see WaitForTask
+func (a *APIClient) VerifyPolicyTask(ctx context.Context, pkg string) (string, error) {
+	resp, err := command[verifyPolicyRequest, taskResponse](ctx, a, endpointVerifyPolicy,
+		verifyPolicyRequest{PolicyPackage: pkg})
+	return resp.TaskID, err
+}
@@ -0,0 +1,183 @@
+package checkptclient
+
+import "context"
+
+const (
+	endpointAddServiceTCP      = `add-service-tcp`
+	endpointShowServiceTCP     = `show-service-tcp`
+	endpointShowServicesTCP    = `show-services-tcp`
+	endpointSetServiceTCP      = `set-service-tcp`
+	endpointDeleteServiceTCP   = `delete-service-tcp`
+	endpointAddServiceUDP      = `add-service-udp`
+	endpointShowServiceUDP     = `show-service-udp`
+	endpointShowServicesUDP    = `show-services-udp`
+	endpointSetServiceUDP      = `set-service-udp`
+	endpointDeleteServiceUDP   = `delete-service-udp`
+	endpointAddServiceICMP     = `add-service-icmp`
+	endpointShowServiceICMP    = `show-service-icmp`
+	endpointShowServicesICMP   = `show-services-icmp`
+	endpointSetServiceICMP     = `set-service-icmp`
+	endpointDeleteServiceICMP  = `delete-service-icmp`
+	endpointAddServiceGroup    = `add-service-group`
+	endpointShowServiceGroup   = `show-service-group`
+	endpointShowServiceGroups  = `show-service-groups`
+	endpointSetServiceGroup    = `set-service-group`
+	endpointDeleteServiceGroup = `delete-service-group`
+)
+
+//CreateServiceTCP creates a TCP service on the CheckPoint service
+func (a *APIClient) CreateServiceTCP(ctx context.Context, svc ServiceTCP) (ServiceTCP, error) {
+	return command[ServiceTCP, ServiceTCP](ctx, a, endpointAddServiceTCP, svc)
+}
+
+//ShowServiceTCP returns a TCP service by uid or name
+func (a *APIClient) ShowServiceTCP(ctx context.Context, id ObjectID) (ServiceTCP, error) {
+	return command[showRequest, ServiceTCP](ctx, a, endpointShowServiceTCP, showRequest{ObjectID: id})
+}
+
+//ShowServicesTCP returns a page of the TCP services matching the
+//options
+func (a *APIClient) ShowServicesTCP(ctx context.Context, opts ListOptions) (ObjectList[ServiceTCP], error) {
+	return command[ListOptions, ObjectList[ServiceTCP]](ctx, a, endpointShowServicesTCP, opts)
+}
+
+//SetServiceTCP changes the TCP service identified by its UID or Name
+func (a *APIClient) SetServiceTCP(ctx context.Context, svc ServiceTCP) (ServiceTCP, error) {
+	return command[ServiceTCP, ServiceTCP](ctx, a, endpointSetServiceTCP, svc)
+}
+
+//DeleteServiceTCP deletes a TCP service by uid or name
+func (a *APIClient) DeleteServiceTCP(ctx context.Context, id ObjectID, opts DeleteOptions) error {
+	_, err := command[deleteRequest, NoMessage](ctx, a, endpointDeleteServiceTCP,
+		deleteRequest{ObjectID: id, DeleteOptions: opts})
+	return err
+}
+
+//CreateServiceUDP creates a UDP service on the CheckPoint service
+func (a *APIClient) CreateServiceUDP(ctx context.Context, svc ServiceUDP) (ServiceUDP, error) {
+	return command[ServiceUDP, ServiceUDP](ctx, a, endpointAddServiceUDP, svc)
+}
+
+//ShowServiceUDP returns a UDP service by uid or name
+func (a *APIClient) ShowServiceUDP(ctx context.Context, id ObjectID) (ServiceUDP, error) {
+	return command[showRequest, ServiceUDP](ctx, a, endpointShowServiceUDP, showRequest{ObjectID: id})
+}
+
+//ShowServicesUDP returns a page of the UDP services matching the
+//options
+func (a *APIClient) ShowServicesUDP(ctx context.Context, opts ListOptions) (ObjectList[ServiceUDP], error) {
+	return command[ListOptions, ObjectList[ServiceUDP]](ctx, a, endpointShowServicesUDP, opts)
+}
+
+//SetServiceUDP changes the UDP service identified by its UID or Name
+func (a *APIClient) SetServiceUDP(ctx context.Context, svc ServiceUDP) (ServiceUDP, error) {
+	return command[ServiceUDP, ServiceUDP](ctx, a, endpointSetServiceUDP, svc)
+}
+
+//DeleteServiceUDP deletes a UDP service by uid or name
+func (a *APIClient) DeleteServiceUDP(ctx context.Context, id ObjectID, opts DeleteOptions) error {
+	_, err := command[deleteRequest, NoMessage](ctx, a, endpointDeleteServiceUDP,
+		deleteRequest{ObjectID: id, DeleteOptions: opts})
+	return err
+}
+
+//CreateServiceICMP creates an ICMP service on the CheckPoint service
+func (a *APIClient) CreateServiceICMP(ctx context.Context, svc ServiceICMP) (ServiceICMP, error) {
+	return command[ServiceICMP, ServiceICMP](ctx, a, endpointAddServiceICMP, svc)
+}
+
+//ShowServiceICMP returns an ICMP service by uid or name
+func (a *APIClient) ShowServiceICMP(ctx context.Context, id ObjectID) (ServiceICMP, error) {
+	return command[showRequest, ServiceICMP](ctx, a, endpointShowServiceICMP, showRequest{ObjectID: id})
+}
+
+//ShowServicesICMP returns a page of the ICMP services matching the
+//options
+func (a *APIClient) ShowServicesICMP(ctx context.Context, opts ListOptions) (ObjectList[ServiceICMP], error) {
+	return command[ListOptions, ObjectList[ServiceICMP]](ctx, a, endpointShowServicesICMP, opts)
+}
+
+//SetServiceICMP changes the ICMP service identified by its UID or
+//Name
+func (a *APIClient) SetServiceICMP(ctx context.Context, svc ServiceICMP) (ServiceICMP, error) {
+	return command[ServiceICMP, ServiceICMP](ctx, a, endpointSetServiceICMP, svc)
+}
+
+//DeleteServiceICMP deletes an ICMP service by uid or name
+func (a *APIClient) DeleteServiceICMP(ctx context.Context, id ObjectID, opts DeleteOptions) error {
+	_, err := command[deleteRequest, NoMessage](ctx, a, endpointDeleteServiceICMP,
+		deleteRequest{ObjectID: id, DeleteOptions: opts})
+	return err
+}
+
+//CreateServiceGroup creates a ServiceGroup on the CheckPoint service
+func (a *APIClient) CreateServiceGroup(ctx context.Context, group ServiceGroup) (ServiceGroup, error) {
+	return command[ServiceGroup, ServiceGroup](ctx, a, endpointAddServiceGroup, group)
+}
+
+//ShowServiceGroup returns a ServiceGroup by uid or name
+func (a *APIClient) ShowServiceGroup(ctx context.Context, id ObjectID) (ServiceGroup, error) {
+	return command[showRequest, ServiceGroup](ctx, a, endpointShowServiceGroup, showRequest{ObjectID: id})
+}
+
+//ShowServiceGroups returns a page of the service groups matching
+//the options
+func (a *APIClient) ShowServiceGroups(ctx context.Context, opts ListOptions) (ObjectList[ServiceGroup], error) {
+	return command[ListOptions, ObjectList[ServiceGroup]](ctx, a, endpointShowServiceGroups, opts)
+}
+
+//SetServiceGroup changes the ServiceGroup identified by its UID or
+//Name. Members, when set, replace the members of the group.
+func (a *APIClient) SetServiceGroup(ctx context.Context, group ServiceGroup) (ServiceGroup, error) {
+	return command[ServiceGroup, ServiceGroup](ctx, a, endpointSetServiceGroup, group)
+}
+
+//AddServiceGroupMembers adds services to a service group, leaving
+//its other members in place
+func (a *APIClient) AddServiceGroupMembers(ctx context.Context, id ObjectID, members ...Member) (ServiceGroup, error) {
+	req := membersRequest{ObjectID: id}
+	req.Members.Add = members
+	return command[membersRequest, ServiceGroup](ctx, a, endpointSetServiceGroup, req)
+}
+
+//RemoveServiceGroupMembers removes services from a service group
+func (a *APIClient) RemoveServiceGroupMembers(ctx context.Context, id ObjectID, members ...Member) (ServiceGroup, error) {
+	req := membersRequest{ObjectID: id}
+	req.Members.Remove = members
+	return command[membersRequest, ServiceGroup](ctx, a, endpointSetServiceGroup, req)
+}
+
+//DeleteServiceGroup deletes a ServiceGroup by uid or name. The
+//members of the group are not deleted.
+func (a *APIClient) DeleteServiceGroup(ctx context.Context, id ObjectID, opts DeleteOptions) error {
+	_, err := command[deleteRequest, NoMessage](ctx, a, endpointDeleteServiceGroup,
+		deleteRequest{ObjectID: id, DeleteOptions: opts})
+	return err
+}
+
+//serviceLookups are the show commands tried by LookupService, in
+//order
+var serviceLookups = []string{
+	endpointShowServiceTCP,
+	endpointShowServiceUDP,
+	endpointShowServiceICMP,
+	endpointShowServiceGroup,
+}
+
+//LookupService resolves a service name, of any service type, to a
+//Member for use as a service reference in rules and service groups.
+//IsNotFound reports true for the error if no service has the name.
+func (a *APIClient) LookupService(ctx context.Context, name string) (Member, error) {
+	var err error
+	for _, cmd := range serviceLookups {
+		var m Member
+		m, err = command[showRequest, Member](ctx, a, cmd, showRequest{ObjectID: ByName(name)})
+		if err == nil {
+			return m, nil
+		}
+		if !IsNotFound(err) {
+			return Member{}, err
+		}
+	}
+	return Member{}, err
+}
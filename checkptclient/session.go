@@ -0,0 +1,177 @@
+package checkptclient
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/ericroys/rest"
+)
+
+const (
+	endpointLogout          = `logout`
+	endpointDiscard         = `discard`
+	endpointKeepAlive       = `keepalive`
+	endpointShowSession     = `show-session`
+	endpointShowSessions    = `show-sessions`
+	endpointSwitchSession   = `switch-session`
+	endpointTakeOverSession = `take-over-session`
+)
+
+//sidHeader is the header holding the session id
+const sidHeader = "X-chkp-sid"
+
+//Logout ends the current session and stops the keepalive loop.
+//Changes pending in the session are kept by the service, see Close
+//to discard or publish them first. It does nothing when the client
+//is not logged in or the session already expired, the next request
+//logs in again.
+func (a *APIClient) Logout(ctx context.Context) error {
+	a.stopKeepAlive()
+	_, _, err := current[NoMessage, NoMessage](ctx, a, endpointLogout, NoMessage{})
+	a.auth.Invalidate()
+	var he *rest.HTTPError
+	if errors.As(err, &he) && a.auth.Expired(he.StatusCode, he.Body) {
+		return nil
+	}
+	return err
+}
+
+//Close applies the config's OnClose action to the changes pending
+//in the current session and logs out. The client should be closed
+//when finished with, in particular when KeepAlive is set. An error
+//discarding or publishing is returned after logging out, the
+//changes are then left in the session.
+func (a *APIClient) Close(ctx context.Context) error {
+	var err error
+	if _, ok := a.auth.Current(); ok {
+		switch a.conf.OnClose {
+		case CloseDiscard:
+			_, err = a.Discard(ctx)
+		case ClosePublish:
+			err = a.Publish(ctx)
+		}
+	}
+	if lerr := a.Logout(ctx); err == nil {
+		err = lerr
+	}
+	return err
+}
+
+//Discard drops the changes pending in the current session and
+//returns the number of changes discarded
+func (a *APIClient) Discard(ctx context.Context) (int, error) {
+	resp, err := command[NoMessage, discardResponse](ctx, a, endpointDiscard, NoMessage{})
+	return resp.Discarded, err
+}
+
+//KeepAlive keeps the current session from timing out
+func (a *APIClient) KeepAlive(ctx context.Context) error {
+	_, err := command[NoMessage, NoMessage](ctx, a, endpointKeepAlive, NoMessage{})
+	return err
+}
+
+//ShowSession returns the session with uid, or the current session
+//when uid is empty
+func (a *APIClient) ShowSession(ctx context.Context, uid string) (SessionInfo, error) {
+	return command[sessionRequest, SessionInfo](ctx, a, endpointShowSession, sessionRequest{UID: uid})
+}
+
+//ShowSessions returns a page of the open sessions, including those
+//of other administrators
+func (a *APIClient) ShowSessions(ctx context.Context, opts ListOptions) (ObjectList[SessionInfo], error) {
+	return command[ListOptions, ObjectList[SessionInfo]](ctx, a, endpointShowSessions, opts)
+}
+
+//SwitchSession switches the client to another session of the same
+//user, e.g. one left with pending changes by an earlier client. The
+//session switched from is closed unless it has pending changes.
+func (a *APIClient) SwitchSession(ctx context.Context, uid string) (SessionInfo, error) {
+	return command[sessionRequest, SessionInfo](ctx, a, endpointSwitchSession, sessionRequest{UID: uid})
+}
+
+//TakeOverSession takes over the session with uid, of any user, and
+//switches the client to it, releasing its locks to the client. A
+//session in use by another client is only taken over when
+//disconnect is set, the other client is then disconnected.
+func (a *APIClient) TakeOverSession(ctx context.Context, uid string, disconnect bool) (SessionInfo, error) {
+	return command[takeOverRequest, SessionInfo](ctx, a, endpointTakeOverSession,
+		takeOverRequest{UID: uid, DisconnectActiveSession: disconnect})
+}
+
+//loggedIn starts the keepalive loop, when the config asks for it,
+//once a request in the session succeeded. It is not started by
+//login, which runs while the session is locked.
+func (a *APIClient) loggedIn() {
+	if !a.conf.KeepAlive {
+		return
+	}
+	a.startKeepAlive(time.Duration(atomic.LoadInt64(&a.sessTimeout)) * time.Second / 2)
+}
+
+//startKeepAlive starts the keepalive loop sending keepalive every
+//interval, unless it is already running
+func (a *APIClient) startKeepAlive(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stop != nil {
+		return
+	}
+	a.stop = make(chan struct{})
+	go a.keepAlive(a.stop, interval)
+}
+
+//stopKeepAlive stops the keepalive loop if it is running
+func (a *APIClient) stopKeepAlive() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stop != nil {
+		close(a.stop)
+		a.stop = nil
+	}
+}
+
+//keepAlive sends keepalive every interval until stop is closed. It
+//only keeps a current session alive and never logs in, a session
+//that expired anyway is left for the next request to log in again.
+func (a *APIClient) keepAlive(stop chan struct{}, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		current[NoMessage, NoMessage](context.Background(), a, endpointKeepAlive, NoMessage{})
+	}
+}
+
+//current sends msg to a Check Point command in the current session
+//without logging in, ok is false and nothing is sent when there is
+//no current session
+func current[Req, Resp any](ctx context.Context, a *APIClient, cmd string, msg Req) (out Resp, ok bool, err error) {
+	sid, ok := a.auth.Current()
+	if !ok {
+		return out, false, nil
+	}
+	uri, err := a.getPath(cmd, "")
+	if err != nil {
+		return out, true, err
+	}
+
+	ctx, cancel := a.withTimeout(ctx)
+	defer cancel()
+
+	b, err := a.getBuilder(ctx, uri, false)
+	if err != nil {
+		return out, true, err
+	}
+	b.Header(sidHeader, sid)
+	out, _, err = rest.DoJSON[Req, Resp](ctx, b, msg)
+	return out, true, err
+}
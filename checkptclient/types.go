@@ -1,5 +1,10 @@
 package checkptclient
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 /* All the structures used in marshal/unmarshal of json
    to and from the Check Point service
 */
@@ -17,10 +22,13 @@ type LoginResponse struct {
 }
 
 //Session struct for defining session parameters
-//used when establishing communication with the service
+//used when establishing communication with the service.
+//The session authenticates with User and Password, or APIKey.
 type Session struct {
-	User         string `json:"user"`
-	Password     string `json:"password"`
+	User         string `json:"user,omitempty"`
+	Password     string `json:"password,omitempty"`
+	APIKey       string `json:"api-key,omitempty"`
+	ReadOnly     bool   `json:"read-only,omitempty"`
 	Domain       string `json:"domain,omitempty"`
 	SessCont     bool   `json:"continue-last-session,omitempty"`
 	SessContPub  bool   `json:"enter-last-published-session,omitempty"`
@@ -30,7 +38,21 @@ type Session struct {
 	SessTimeout  int    `json:"session-timeout,omitempty"`
 }
 
-//Host struct for definining and marshal/unmarshal of Host object
+//String formats the session with its password and api key masked,
+//so that the credentials are not logged
+func (s Session) String() string {
+	type session Session
+	for _, c := range []*string{&s.Password, &s.APIKey} {
+		if len(*c) > 0 {
+			*c = "*****"
+		}
+	}
+	return fmt.Sprintf("%+v", session(s))
+}
+
+//Host struct for definining and marshal/unmarshal of Host object.
+//NatSettings is only sent when not zero, so that SetHost leaves the
+//NAT configuration of a host unchanged unless given.
 type Host struct {
 	UID         string `json:"uid,omitempty"`
 	Name        string `json:"name,omitempty"`
@@ -40,6 +62,16 @@ type Host struct {
 	NatSettings `json:"nat-settings,omitempty"`
 }
 
+//MarshalJSON implements json.Marshaler, omitting zero NatSettings
+func (h Host) MarshalJSON() ([]byte, error) {
+	type host Host
+	v := struct {
+		host
+		NatSettings *NatSettings `json:"nat-settings,omitempty"`
+	}{host(h), h.NatSettings.sent()}
+	return json.Marshal(v)
+}
+
 //NatSettings struct for defining and marshal/unmarshal of NatSettings object
 type NatSettings struct {
 	Hidebehind string `json:"hide-behind,omitempty"`
@@ -49,6 +81,839 @@ type NatSettings struct {
 	Method     string `json:"method,omitempty"`
 }
 
+//sent returns the settings to send with an object, nil when zero
+//so that they are left out
+func (n NatSettings) sent() *NatSettings {
+	if n == (NatSettings{}) {
+		return nil
+	}
+	return &n
+}
+
+//Network struct for defining and marshal/unmarshal of Network
+//object, an IPv4 and/or IPv6 subnet
+type Network struct {
+	UID         string `json:"uid,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Newname     string `json:"new-name,omitempty"`
+	Subnet4     string `json:"subnet4,omitempty"`
+	MaskLength4 int    `json:"mask-length4,omitempty"`
+	Subnet6     string `json:"subnet6,omitempty"`
+	MaskLength6 int    `json:"mask-length6,omitempty"`
+	Broadcast   string `json:"broadcast,omitempty"`
+	Color       string `json:"color,omitempty"`
+	Comments    string `json:"comments,omitempty"`
+	NatSettings `json:"nat-settings,omitempty"`
+}
+
+//MarshalJSON implements json.Marshaler, omitting zero NatSettings
+func (n Network) MarshalJSON() ([]byte, error) {
+	type network Network
+	v := struct {
+		network
+		NatSettings *NatSettings `json:"nat-settings,omitempty"`
+	}{network(n), n.NatSettings.sent()}
+	return json.Marshal(v)
+}
+
+//AddressRange struct for defining and marshal/unmarshal of
+//AddressRange object, the IPv4 and/or IPv6 addresses from
+//first to last
+type AddressRange struct {
+	UID              string `json:"uid,omitempty"`
+	Name             string `json:"name,omitempty"`
+	Newname          string `json:"new-name,omitempty"`
+	Ipv4addressFirst string `json:"ipv4-address-first,omitempty"`
+	Ipv4addressLast  string `json:"ipv4-address-last,omitempty"`
+	Ipv6addressFirst string `json:"ipv6-address-first,omitempty"`
+	Ipv6addressLast  string `json:"ipv6-address-last,omitempty"`
+	Color            string `json:"color,omitempty"`
+	Comments         string `json:"comments,omitempty"`
+	NatSettings      `json:"nat-settings,omitempty"`
+}
+
+//MarshalJSON implements json.Marshaler, omitting zero NatSettings
+func (r AddressRange) MarshalJSON() ([]byte, error) {
+	type addressRange AddressRange
+	v := struct {
+		addressRange
+		NatSettings *NatSettings `json:"nat-settings,omitempty"`
+	}{addressRange(r), r.NatSettings.sent()}
+	return json.Marshal(v)
+}
+
+//Group struct for defining and marshal/unmarshal of Group object.
+//Members set on SetGroup replace all members of the group, see
+//AddGroupMembers and RemoveGroupMembers to change some of them.
+type Group struct {
+	UID      string   `json:"uid,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Newname  string   `json:"new-name,omitempty"`
+	Members  []Member `json:"members,omitempty"`
+	Color    string   `json:"color,omitempty"`
+	Comments string   `json:"comments,omitempty"`
+}
+
+//Member is a member of a group, or an object referenced by a rule.
+//Members are sent by uid, or name if there is no uid, and are
+//returned with uid, name and type
+type Member struct {
+	UID  string `json:"uid,omitempty"`
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+}
+
+//MarshalJSON implements json.Marshaler, a member is sent as its
+//uid or name
+func (m Member) MarshalJSON() ([]byte, error) {
+	if len(m.UID) > 0 {
+		return json.Marshal(m.UID)
+	}
+	return json.Marshal(m.Name)
+}
+
+//UnmarshalJSON implements json.Unmarshaler for members returned
+//as objects, or as uids with details level uid
+func (m *Member) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*m = Member{}
+		return json.Unmarshal(data, &m.UID)
+	}
+	type member Member
+	return json.Unmarshal(data, (*member)(m))
+}
+
+//membersRequest is the message to add or remove members of a group
+type membersRequest struct {
+	ObjectID
+	Members struct {
+		Add    []Member `json:"add,omitempty"`
+		Remove []Member `json:"remove,omitempty"`
+	} `json:"members"`
+}
+
+//ServiceTCP struct for defining and marshal/unmarshal of
+//service-tcp object. Port and SourcePort are a port, a range
+//such as "5000-5100", or a comparison such as ">1024".
+type ServiceTCP struct {
+	UID                      string `json:"uid,omitempty"`
+	Name                     string `json:"name,omitempty"`
+	Newname                  string `json:"new-name,omitempty"`
+	Port                     string `json:"port,omitempty"`
+	SourcePort               string `json:"source-port,omitempty"`
+	Protocol                 string `json:"protocol,omitempty"`
+	MatchForAny              *bool  `json:"match-for-any,omitempty"`
+	SessionTimeout           int    `json:"session-timeout,omitempty"`
+	UseDefaultSessionTimeout *bool  `json:"use-default-session-timeout,omitempty"`
+	KeepConnectionsOpen      *bool  `json:"keep-connections-open-after-policy-installation,omitempty"`
+	SyncConnectionsOnCluster *bool  `json:"sync-connections-on-cluster,omitempty"`
+	Color                    string `json:"color,omitempty"`
+	Comments                 string `json:"comments,omitempty"`
+}
+
+//ServiceUDP struct for defining and marshal/unmarshal of
+//service-udp object, see ServiceTCP for the port formats
+type ServiceUDP struct {
+	UID                      string `json:"uid,omitempty"`
+	Name                     string `json:"name,omitempty"`
+	Newname                  string `json:"new-name,omitempty"`
+	Port                     string `json:"port,omitempty"`
+	SourcePort               string `json:"source-port,omitempty"`
+	Protocol                 string `json:"protocol,omitempty"`
+	AcceptReplies            *bool  `json:"accept-replies,omitempty"`
+	MatchForAny              *bool  `json:"match-for-any,omitempty"`
+	SessionTimeout           int    `json:"session-timeout,omitempty"`
+	UseDefaultSessionTimeout *bool  `json:"use-default-session-timeout,omitempty"`
+	KeepConnectionsOpen      *bool  `json:"keep-connections-open-after-policy-installation,omitempty"`
+	SyncConnectionsOnCluster *bool  `json:"sync-connections-on-cluster,omitempty"`
+	Color                    string `json:"color,omitempty"`
+	Comments                 string `json:"comments,omitempty"`
+}
+
+//ServiceICMP struct for defining and marshal/unmarshal of
+//service-icmp object. The ICMP type and code are pointers as 0
+//is a valid type (echo reply) and code.
+type ServiceICMP struct {
+	UID                 string `json:"uid,omitempty"`
+	Name                string `json:"name,omitempty"`
+	Newname             string `json:"new-name,omitempty"`
+	IcmpType            *int   `json:"icmp-type,omitempty"`
+	IcmpCode            *int   `json:"icmp-code,omitempty"`
+	KeepConnectionsOpen *bool  `json:"keep-connections-open-after-policy-installation,omitempty"`
+	Color               string `json:"color,omitempty"`
+	Comments            string `json:"comments,omitempty"`
+}
+
+//ServiceGroup struct for defining and marshal/unmarshal of
+//service-group object. Members set on SetServiceGroup replace all
+//members of the group.
+type ServiceGroup struct {
+	UID      string   `json:"uid,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Newname  string   `json:"new-name,omitempty"`
+	Members  []Member `json:"members,omitempty"`
+	Color    string   `json:"color,omitempty"`
+	Comments string   `json:"comments,omitempty"`
+}
+
+//Bool returns a pointer to b for the optional flags of objects
+func Bool(b bool) *bool {
+	return &b
+}
+
+//Int returns a pointer to i for optional numbers of objects
+func Int(i int) *int {
+	return &i
+}
+
+//Position is the position of a new rule or section in a rulebase,
+//or of a rule moved by a set command. Use one of PositionTop,
+//PositionBottom, PositionAbove, PositionBelow, PositionTopOf,
+//PositionBottomOf or PositionNumber.
+type Position struct {
+	value interface{}
+}
+
+//PositionTop is the top of the rulebase
+func PositionTop() *Position {
+	return &Position{"top"}
+}
+
+//PositionBottom is the bottom of the rulebase
+func PositionBottom() *Position {
+	return &Position{"bottom"}
+}
+
+//PositionAbove is directly above the rule or section with uid or
+//name id
+func PositionAbove(id string) *Position {
+	return &Position{map[string]string{"above": id}}
+}
+
+//PositionBelow is directly below the rule or section with uid or
+//name id
+func PositionBelow(id string) *Position {
+	return &Position{map[string]string{"below": id}}
+}
+
+//PositionTopOf is the top of the section with uid or name section
+func PositionTopOf(section string) *Position {
+	return &Position{map[string]string{"top": section}}
+}
+
+//PositionBottomOf is the bottom of the section with uid or name
+//section
+func PositionBottomOf(section string) *Position {
+	return &Position{map[string]string{"bottom": section}}
+}
+
+//PositionNumber is the position of the rule numbered n, the rule
+//there and those below move down
+func PositionNumber(n int) *Position {
+	return &Position{n}
+}
+
+//MarshalJSON implements json.Marshaler
+func (p Position) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.value)
+}
+
+//AccessLayer struct for defining and marshal/unmarshal of
+//access-layer object
+type AccessLayer struct {
+	UID                   string `json:"uid,omitempty"`
+	Name                  string `json:"name,omitempty"`
+	Newname               string `json:"new-name,omitempty"`
+	Firewall              *bool  `json:"firewall,omitempty"`
+	ApplicationsAndURL    *bool  `json:"applications-and-url-filtering,omitempty"`
+	ContentAwareness      *bool  `json:"content-awareness,omitempty"`
+	MobileAccess          *bool  `json:"mobile-access,omitempty"`
+	Shared                *bool  `json:"shared,omitempty"`
+	ImplicitCleanupAction string `json:"implicit-cleanup-action,omitempty"`
+	Color                 string `json:"color,omitempty"`
+	Comments              string `json:"comments,omitempty"`
+}
+
+//AccessLayerList is a page of access layers
+type AccessLayerList struct {
+	From   int           `json:"from"`
+	To     int           `json:"to"`
+	Total  int           `json:"total"`
+	Layers []AccessLayer `json:"access-layers"`
+}
+
+//More reports whether access layers follow the page
+func (l AccessLayerList) More() bool {
+	return l.To < l.Total
+}
+
+//Track is the logging of the connections matching a rule. Type is
+//one of the tracks "None", "Log", "Extended Log" or "Detailed Log".
+type Track struct {
+	Type          *Member `json:"type,omitempty"`
+	PerConnection *bool   `json:"per-connection,omitempty"`
+	PerSession    *bool   `json:"per-session,omitempty"`
+	Accounting    *bool   `json:"accounting,omitempty"`
+	Alert         string  `json:"alert,omitempty"`
+}
+
+//AccessRule struct for defining and marshal/unmarshal of
+//access-rule object. Source, Destination, Service, InstallOn and
+//Time reference objects by uid or name, an empty list is "Any".
+//Action references one of "Accept", "Drop", "Reject", "Ask",
+//"Inform" or "Apply Layer".
+//
+//Lists set on SetAccessRule replace the current list.
+type AccessRule struct {
+	UID               string    `json:"uid,omitempty"`
+	Name              string    `json:"name,omitempty"`
+	Newname           string    `json:"new-name,omitempty"`
+	Layer             string    `json:"layer,omitempty"`
+	Position          *Position `json:"position,omitempty"`
+	NewPosition       *Position `json:"new-position,omitempty"`
+	RuleNumber        int       `json:"rule-number,omitempty"`
+	Enabled           *bool     `json:"enabled,omitempty"`
+	Action            *Member   `json:"action,omitempty"`
+	Source            []Member  `json:"source,omitempty"`
+	SourceNegate      *bool     `json:"source-negate,omitempty"`
+	Destination       []Member  `json:"destination,omitempty"`
+	DestinationNegate *bool     `json:"destination-negate,omitempty"`
+	Service           []Member  `json:"service,omitempty"`
+	ServiceNegate     *bool     `json:"service-negate,omitempty"`
+	Track             *Track    `json:"track,omitempty"`
+	InstallOn         []Member  `json:"install-on,omitempty"`
+	Time              []Member  `json:"time,omitempty"`
+	Comments          string    `json:"comments,omitempty"`
+}
+
+//AccessSection struct for defining and marshal/unmarshal of
+//access-section object, a titled group of consecutive rules. From
+//and To are the numbers of the section's first and last rule.
+type AccessSection struct {
+	UID      string       `json:"uid,omitempty"`
+	Name     string       `json:"name,omitempty"`
+	Newname  string       `json:"new-name,omitempty"`
+	Layer    string       `json:"layer,omitempty"`
+	Position *Position    `json:"position,omitempty"`
+	From     int          `json:"from,omitempty"`
+	To       int          `json:"to,omitempty"`
+	Rulebase []AccessRule `json:"rulebase,omitempty"`
+}
+
+//AccessRulebaseEntry is an entry of a rulebase page, either a Rule
+//or a Section with its rules on the page
+type AccessRulebaseEntry struct {
+	Rule    *AccessRule
+	Section *AccessSection
+}
+
+//UnmarshalJSON implements json.Unmarshaler, by the entry's type
+func (e *AccessRulebaseEntry) UnmarshalJSON(data []byte) error {
+	var t struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	*e = AccessRulebaseEntry{}
+	if t.Type == "access-section" {
+		e.Section = &AccessSection{}
+		return json.Unmarshal(data, e.Section)
+	}
+	e.Rule = &AccessRule{}
+	return json.Unmarshal(data, e.Rule)
+}
+
+//AccessRulebase is a page of the rules of an access layer. The
+//rules' references are resolved with the objects dictionary of the
+//page, so each has its uid, name and type.
+type AccessRulebase struct {
+	UID      string                `json:"uid"`
+	Name     string                `json:"name"`
+	From     int                   `json:"from"`
+	To       int                   `json:"to"`
+	Total    int                   `json:"total"`
+	Rulebase []AccessRulebaseEntry `json:"rulebase"`
+	Objects  []Member              `json:"objects-dictionary"`
+}
+
+//More reports whether rules follow the page
+func (r AccessRulebase) More() bool {
+	return r.To < r.Total
+}
+
+//Rules returns the rules of the page in order, including those in
+//sections
+func (r AccessRulebase) Rules() []AccessRule {
+	var rules []AccessRule
+	for _, e := range r.Rulebase {
+		if e.Section != nil {
+			rules = append(rules, e.Section.Rulebase...)
+		} else if e.Rule != nil {
+			rules = append(rules, *e.Rule)
+		}
+	}
+	return rules
+}
+
+//dereference resolves the references of the page's rules, returned
+//as uids, to the objects of the dictionary
+func (r *AccessRulebase) dereference() {
+	dict := dictionary(r.Objects)
+	for i := range r.Rulebase {
+		e := &r.Rulebase[i]
+		if e.Rule != nil {
+			e.Rule.dereference(dict)
+		}
+		if e.Section != nil {
+			for j := range e.Section.Rulebase {
+				e.Section.Rulebase[j].dereference(dict)
+			}
+		}
+	}
+}
+
+//dereference resolves the references of the rule with dict
+func (r *AccessRule) dereference(dict map[string]Member) {
+	resolve(dict, r.Action)
+	if r.Track != nil {
+		resolve(dict, r.Track.Type)
+	}
+	for _, l := range [][]Member{r.Source, r.Destination, r.Service, r.InstallOn, r.Time} {
+		for i := range l {
+			resolve(dict, &l[i])
+		}
+	}
+}
+
+//Methods of NAT rules
+const (
+	NatMethodStatic = "static"
+	NatMethodHide   = "hide"
+	NatMethodNat64  = "nat64"
+	NatMethodNat46  = "nat46"
+)
+
+//NatRule struct for defining and marshal/unmarshal of nat-rule
+//object, a manual NAT rule of the Package's NAT policy. The original
+//fields reference the objects matched, "Any" when not set, and the
+//translated fields the objects they are translated to, "Original"
+//when not set. Method is one of the NatMethod constants.
+type NatRule struct {
+	UID                   string    `json:"uid,omitempty"`
+	Name                  string    `json:"name,omitempty"`
+	Newname               string    `json:"new-name,omitempty"`
+	Package               string    `json:"package,omitempty"`
+	Position              *Position `json:"position,omitempty"`
+	NewPosition           *Position `json:"new-position,omitempty"`
+	RuleNumber            int       `json:"rule-number,omitempty"`
+	Enabled               *bool     `json:"enabled,omitempty"`
+	Method                string    `json:"method,omitempty"`
+	OriginalSource        *Member   `json:"original-source,omitempty"`
+	OriginalDestination   *Member   `json:"original-destination,omitempty"`
+	OriginalService       *Member   `json:"original-service,omitempty"`
+	TranslatedSource      *Member   `json:"translated-source,omitempty"`
+	TranslatedDestination *Member   `json:"translated-destination,omitempty"`
+	TranslatedService     *Member   `json:"translated-service,omitempty"`
+	InstallOn             []Member  `json:"install-on,omitempty"`
+	Comments              string    `json:"comments,omitempty"`
+}
+
+//NatSection struct for defining and marshal/unmarshal of
+//nat-section object, see AccessSection
+type NatSection struct {
+	UID      string    `json:"uid,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Newname  string    `json:"new-name,omitempty"`
+	Package  string    `json:"package,omitempty"`
+	Position *Position `json:"position,omitempty"`
+	From     int       `json:"from,omitempty"`
+	To       int       `json:"to,omitempty"`
+	Rulebase []NatRule `json:"rulebase,omitempty"`
+}
+
+//NatRulebaseEntry is an entry of a NAT rulebase page, either a Rule
+//or a Section with its rules on the page
+type NatRulebaseEntry struct {
+	Rule    *NatRule
+	Section *NatSection
+}
+
+//UnmarshalJSON implements json.Unmarshaler, by the entry's type
+func (e *NatRulebaseEntry) UnmarshalJSON(data []byte) error {
+	var t struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	*e = NatRulebaseEntry{}
+	if t.Type == "nat-section" {
+		e.Section = &NatSection{}
+		return json.Unmarshal(data, e.Section)
+	}
+	e.Rule = &NatRule{}
+	return json.Unmarshal(data, e.Rule)
+}
+
+//NatRulebase is a page of the NAT rules of a package, with the
+//rules' references resolved as for AccessRulebase
+type NatRulebase struct {
+	UID      string             `json:"uid"`
+	Name     string             `json:"name"`
+	From     int                `json:"from"`
+	To       int                `json:"to"`
+	Total    int                `json:"total"`
+	Rulebase []NatRulebaseEntry `json:"rulebase"`
+	Objects  []Member           `json:"objects-dictionary"`
+}
+
+//More reports whether rules follow the page
+func (r NatRulebase) More() bool {
+	return r.To < r.Total
+}
+
+//Rules returns the rules of the page in order, including those in
+//sections
+func (r NatRulebase) Rules() []NatRule {
+	var rules []NatRule
+	for _, e := range r.Rulebase {
+		if e.Section != nil {
+			rules = append(rules, e.Section.Rulebase...)
+		} else if e.Rule != nil {
+			rules = append(rules, *e.Rule)
+		}
+	}
+	return rules
+}
+
+//dereference resolves the references of the page's rules, returned
+//as uids, to the objects of the dictionary
+func (r *NatRulebase) dereference() {
+	dict := dictionary(r.Objects)
+	for i := range r.Rulebase {
+		e := &r.Rulebase[i]
+		if e.Rule != nil {
+			e.Rule.dereference(dict)
+		}
+		if e.Section != nil {
+			for j := range e.Section.Rulebase {
+				e.Section.Rulebase[j].dereference(dict)
+			}
+		}
+	}
+}
+
+//dereference resolves the references of the rule with dict
+func (r *NatRule) dereference(dict map[string]Member) {
+	for _, m := range []*Member{r.OriginalSource, r.OriginalDestination, r.OriginalService,
+		r.TranslatedSource, r.TranslatedDestination, r.TranslatedService} {
+		resolve(dict, m)
+	}
+	for i := range r.InstallOn {
+		resolve(dict, &r.InstallOn[i])
+	}
+}
+
+//RulebaseOptions are the filtering and paging options of show
+//commands for rulebases. Limit and Offset count rules, sections are
+//not counted.
+type RulebaseOptions struct {
+	//Filter matches rules by name, comments or referenced objects
+	Filter string `json:"filter,omitempty"`
+	//Limit is the maximum number of rules returned, the service
+	//defaults to 50 and allows up to 500
+	Limit int `json:"limit,omitempty"`
+	//Offset is the number of rules skipped
+	Offset int `json:"offset,omitempty"`
+	//DetailsLevel is DetailsStandard or DetailsFull
+	DetailsLevel string `json:"details-level,omitempty"`
+}
+
+//rulebaseRequest is the message for show commands of rulebases
+type rulebaseRequest struct {
+	ObjectID
+	RulebaseOptions
+	UseObjectDictionary bool `json:"use-object-dictionary"`
+}
+
+//natRulebaseRequest is the message to show the NAT rulebase of a
+//package
+type natRulebaseRequest struct {
+	Package string `json:"package"`
+	RulebaseOptions
+	UseObjectDictionary bool `json:"use-object-dictionary"`
+}
+
+//ruleRequest identifies a rule or section in a layer for show and
+//delete commands
+type ruleRequest struct {
+	ObjectID
+	Layer string `json:"layer"`
+}
+
+//natRuleRequest identifies a NAT rule or section in a package for
+//show and delete commands
+type natRuleRequest struct {
+	ObjectID
+	Package string `json:"package"`
+}
+
+//dictionary indexes the objects of an objects dictionary by uid
+func dictionary(objects []Member) map[string]Member {
+	dict := make(map[string]Member, len(objects))
+	for _, o := range objects {
+		dict[o.UID] = o
+	}
+	return dict
+}
+
+//resolve replaces a reference returned as a uid with its object in
+//dict
+func resolve(dict map[string]Member, m *Member) {
+	if m == nil || len(m.Name) > 0 {
+		return
+	}
+	if o, ok := dict[m.UID]; ok {
+		*m = o
+	}
+}
+
+//ObjectID identifies an object by uid or name. The uid is used
+//when both are set
+type ObjectID struct {
+	UID  string `json:"uid,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+//ByName returns the ObjectID for an object name
+func ByName(name string) ObjectID {
+	return ObjectID{Name: name}
+}
+
+//ByUID returns the ObjectID for an object uid
+func ByUID(uid string) ObjectID {
+	return ObjectID{UID: uid}
+}
+
+//Details levels of objects returned by show commands
+const (
+	DetailsStandard = "standard"
+	DetailsFull     = "full"
+)
+
+//showRequest is the message for show commands of single objects
+type showRequest struct {
+	ObjectID
+	DetailsLevel string `json:"details-level,omitempty"`
+}
+
+//DeleteOptions are the options of delete commands
+type DeleteOptions struct {
+	//IgnoreWarnings deletes the object despite warnings
+	IgnoreWarnings bool `json:"ignore-warnings,omitempty"`
+	//IgnoreErrors deletes the object despite errors, this
+	//may leave the database in an invalid state
+	IgnoreErrors bool `json:"ignore-errors,omitempty"`
+}
+
+//deleteRequest is the message for delete commands
+type deleteRequest struct {
+	ObjectID
+	DeleteOptions
+}
+
+//ListOptions are the filtering and paging options of show
+//commands for lists of objects
+type ListOptions struct {
+	//Filter matches objects by name, address or comments
+	Filter string `json:"filter,omitempty"`
+	//Limit is the maximum number of objects returned, the
+	//service defaults to 50 and allows up to 500
+	Limit int `json:"limit,omitempty"`
+	//Offset is the number of objects skipped
+	Offset int `json:"offset,omitempty"`
+	//DetailsLevel is DetailsStandard or DetailsFull
+	DetailsLevel string `json:"details-level,omitempty"`
+}
+
+//ObjectList is a page of objects returned by a show command for
+//lists of objects. From and To are the 1 based positions of the
+//first and last object of the page in the Total matching objects.
+type ObjectList[T any] struct {
+	From    int `json:"from"`
+	To      int `json:"to"`
+	Total   int `json:"total"`
+	Objects []T `json:"objects"`
+}
+
+//More reports whether objects follow the page
+func (l ObjectList[T]) More() bool {
+	return l.To < l.Total
+}
+
+//Statuses of tasks
+const (
+	TaskInProgress            = "in progress"
+	TaskSucceeded             = "succeeded"
+	TaskSucceededWithWarnings = "succeeded with warnings"
+	TaskPartiallySucceeded    = "partially succeeded"
+	TaskFailed                = "failed"
+)
+
+//Task is an asynchronous operation of the Check Point service, such
+//as a publish or a policy installation, as returned by show-task
+type Task struct {
+	TaskID             string       `json:"task-id"`
+	TaskName           string       `json:"task-name"`
+	Status             string       `json:"status"`
+	ProgressPercentage int          `json:"progress-percentage"`
+	Suppressed         bool         `json:"suppressed"`
+	Comments           string       `json:"comments,omitempty"`
+	TaskDetails        []TaskDetail `json:"task-details,omitempty"`
+}
+
+//Done reports whether the task is no longer in progress
+func (t Task) Done() bool {
+	return t.Status != TaskInProgress
+}
+
+//Err returns a *TaskError if the task failed or only partially
+//succeeded, nil otherwise
+func (t Task) Err() error {
+	if t.Status == TaskFailed || t.Status == TaskPartiallySucceeded {
+		return &TaskError{Task: t}
+	}
+	return nil
+}
+
+//TaskDetail is a detail of a task, e.g. the result of a policy
+//installation on one gateway or the response of a publish
+type TaskDetail struct {
+	UID               string           `json:"uid,omitempty"`
+	GatewayID         string           `json:"gatewayId,omitempty"`
+	GatewayName       string           `json:"gatewayName,omitempty"`
+	StatusCode        string           `json:"statusCode,omitempty"`
+	StatusDescription string           `json:"statusDescription,omitempty"`
+	TaskNotification  string           `json:"taskNotification,omitempty"`
+	PublishResponse   *PublishResponse `json:"publishResponse,omitempty"`
+}
+
+//PublishResponse is the task detail of a publish
+type PublishResponse struct {
+	NumberOfPublishedChanges int    `json:"numberOfPublishedChanges"`
+	Mode                     string `json:"mode"`
+}
+
+//taskResponse is the response of commands starting a task
+type taskResponse struct {
+	TaskID string `json:"task-id"`
+}
+
+//showTaskRequest is the message for show-task
+type showTaskRequest struct {
+	TaskID       []string `json:"task-id"`
+	DetailsLevel string   `json:"details-level,omitempty"`
+}
+
+//InstallPolicyRequest is the policy package and targets of a
+//policy installation. Unset flags take the service's defaults,
+//installing the access and threat prevention policies of the
+//package on all its installation targets.
+type InstallPolicyRequest struct {
+	PolicyPackage string   `json:"policy-package"`
+	Targets       []string `json:"targets,omitempty"`
+	Access        *bool    `json:"access,omitempty"`
+	//ThreatPrevention installs the threat prevention policy
+	ThreatPrevention *bool `json:"threat-prevention,omitempty"`
+	//InstallOnAllClusterMembersOrFail fails the installation on
+	//a cluster unless it succeeds on all members
+	InstallOnAllClusterMembersOrFail *bool `json:"install-on-all-cluster-members-or-fail,omitempty"`
+	//PrepareOnly compiles the policy without installing it
+	PrepareOnly *bool `json:"prepare-only,omitempty"`
+}
+
+//verifyPolicyRequest is the message for verify-policy
+type verifyPolicyRequest struct {
+	PolicyPackage string `json:"policy-package"`
+}
+
+//CloseAction is what APIClient.Close does with the changes pending
+//in the session before logging out
+type CloseAction int
+
+const (
+	//CloseKeep leaves pending changes in the session, to be
+	//continued by a later login or taken over
+	CloseKeep CloseAction = iota
+	//CloseDiscard discards pending changes
+	CloseDiscard
+	//ClosePublish publishes pending changes
+	ClosePublish
+)
+
+//SessionInfo is a management session as returned by show-session.
+//Changes and Locks count the objects changed and locked by the
+//session, ExpiredSession is set when no client is connected to it.
+type SessionInfo struct {
+	UID            string `json:"uid"`
+	Name           string `json:"name,omitempty"`
+	Type           string `json:"type,omitempty"`
+	UserName       string `json:"user-name"`
+	Description    string `json:"description,omitempty"`
+	Application    string `json:"application,omitempty"`
+	State          string `json:"state"`
+	ConnectionMode string `json:"connection-mode,omitempty"`
+	Changes        int    `json:"changes"`
+	Locks          int    `json:"locks"`
+	InWork         bool   `json:"in-work"`
+	ExpiredSession bool   `json:"expired-session"`
+	SessTimeout    int    `json:"session-timeout,omitempty"`
+}
+
+//sessionRequest is the message for show-session and
+//switch-session, an empty UID is the current session
+type sessionRequest struct {
+	UID string `json:"uid,omitempty"`
+}
+
+//takeOverRequest is the message for take-over-session
+type takeOverRequest struct {
+	UID                     string `json:"uid"`
+	DisconnectActiveSession bool   `json:"disconnect-active-session,omitempty"`
+}
+
+//discardResponse is the response of discard
+type discardResponse struct {
+	Discarded int `json:"number-of-discarded-changes"`
+}
+
+//Domain is a domain of a Multi-Domain Server as returned by
+//show-domains
+type Domain struct {
+	UID      string         `json:"uid"`
+	Name     string         `json:"name"`
+	Type     string         `json:"type,omitempty"`
+	Comments string         `json:"comments,omitempty"`
+	Servers  []DomainServer `json:"servers,omitempty"`
+}
+
+//DomainServer is a server of a domain, running on one of the
+//Multi-Domain Servers
+type DomainServer struct {
+	Name              string `json:"name"`
+	Type              string `json:"type,omitempty"`
+	IPv4Address       string `json:"ipv4-address,omitempty"`
+	MultiDomainServer string `json:"multi-domain-server,omitempty"`
+	Active            bool   `json:"active"`
+}
+
+//loginToDomainRequest is the message for login-to-domain
+type loginToDomainRequest struct {
+	Domain   string `json:"domain"`
+	SessCont bool   `json:"continue-last-session,omitempty"`
+}
+
 //ErrMsgObj is an embedded message object for errors, warnings
 //and blocking errors
 type ErrMsgObj struct {
@@ -29,3 +29,26 @@ func TestLoginResponse(t *testing.T) {
 	fmt.Printf("%d", i.SessTimeout)
 
 }
+
+func TestNatSettings(t *testing.T) {
+	nat := NatSettings{Method: "hide"}
+	for _, tc := range []struct {
+		o    interface{}
+		want string
+	}{
+		{Host{Name: "web1"}, `{"name":"web1"}`},
+		{Host{Name: "web1", NatSettings: nat},
+			`{"name":"web1","nat-settings":{"auto-rule":false,"method":"hide"}}`},
+		{Network{Name: "net1"}, `{"name":"net1"}`},
+		{Network{Name: "net1", NatSettings: nat},
+			`{"name":"net1","nat-settings":{"auto-rule":false,"method":"hide"}}`},
+		{AddressRange{Name: "range1"}, `{"name":"range1"}`},
+		{AddressRange{Name: "range1", NatSettings: nat},
+			`{"name":"range1","nat-settings":{"auto-rule":false,"method":"hide"}}`},
+	} {
+		data, err := json.Marshal(tc.o)
+		if err != nil || string(data) != tc.want {
+			t.Fatalf("Expected %s, got %s %v", tc.want, data, err)
+		}
+	}
+}
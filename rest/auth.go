@@ -0,0 +1,286 @@
+package rest
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Authenticator is interface for the adding
+//appropriate authentication information to an
+// *http.Request. SetAuth is called for every attempt
+//to send a request, and an error aborts the request.
+type Authenticator interface {
+	SetAuth(req *http.Request) error
+}
+
+//RefreshableAuthenticator is an Authenticator for credentials that
+//expire, such as a session or token obtained by logging in. If a
+//response shows the credentials expired, the Request refreshes them
+//and sends the request once more.
+type RefreshableAuthenticator interface {
+	Authenticator
+	//Refresh obtains new credentials
+	Refresh(ctx context.Context) error
+	//Expired reports whether a response with the given status code
+	//and body was rejected because the credentials are no longer valid
+	Expired(code int, data []byte) bool
+}
+
+//AuthNoAuth is an Authenticator the provides
+//no authentication for an *http.Request
+type AuthNoAuth struct {
+}
+
+//SetAuth implements a do nothing implementation of
+//Authenticator.SetAuth()
+func (na AuthNoAuth) SetAuth(req *http.Request) error {
+	//remove any auth header
+	req.Header.Del("Authorization")
+	return nil
+}
+
+//AuthBasic is an Authenticator for adding Basic
+//authentication to an *http.Request
+type AuthBasic struct {
+	user string
+	pass string
+}
+
+//NewAuthBasic creates an AuthBasic for the user and password
+func NewAuthBasic(user, pass string) AuthBasic {
+	return AuthBasic{user: user, pass: pass}
+}
+
+func (b *AuthBasic) getToken() string {
+	s := fmt.Sprintf("%s:%s", b.user, b.pass)
+	sEnc := b64.StdEncoding.EncodeToString([]byte(s))
+	return sEnc
+}
+
+//SetAuth sets Basic Authentication info to an *http.Request
+func (b AuthBasic) SetAuth(req *http.Request) error {
+	t := b.getToken()
+	req.Header.Set("Authorization", "Basic "+t)
+	return nil
+}
+
+//AuthBearer is an Authenticator for adding Bearer
+//authentication to an *http.Request
+type AuthBearer struct {
+	token string
+}
+
+//NewAuthBearer creates an AuthBearer for the token
+func NewAuthBearer(token string) AuthBearer {
+	return AuthBearer{token: token}
+}
+
+//SetAuth sets Bearer token authentication for an *http.Request
+func (b AuthBearer) SetAuth(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	return nil
+}
+
+//AuthAPIKey is an Authenticator for adding an api key
+//header to an *http.Request
+type AuthAPIKey struct {
+	header string
+	key    string
+}
+
+//NewAuthAPIKey creates an AuthAPIKey setting header to key,
+//e.g. NewAuthAPIKey("X-API-Key", key)
+func NewAuthAPIKey(header, key string) AuthAPIKey {
+	return AuthAPIKey{header: header, key: key}
+}
+
+//SetAuth sets the api key header for an *http.Request
+func (k AuthAPIKey) SetAuth(req *http.Request) error {
+	if len(k.header) == 0 {
+		return fmt.Errorf("api key header is not set")
+	}
+	req.Header.Set(k.header, k.key)
+	return nil
+}
+
+//AuthARJWT is an Authenticator for adding a BMC Remedy
+//"AR-JWT <token>" authorization to an *http.Request
+type AuthARJWT struct {
+	token string
+}
+
+//NewAuthARJWT creates an AuthARJWT for a token obtained from
+//the Remedy jwt/login endpoint
+func NewAuthARJWT(token string) AuthARJWT {
+	return AuthARJWT{token: token}
+}
+
+//SetAuth sets AR-JWT authorization for an *http.Request
+func (j AuthARJWT) SetAuth(req *http.Request) error {
+	req.Header.Set("Authorization", "AR-JWT "+j.token)
+	return nil
+}
+
+//AuthCheckPointSID is an Authenticator for adding a Check Point
+//session id, as returned by the login command, to an *http.Request
+type AuthCheckPointSID struct {
+	sid string
+}
+
+//NewAuthCheckPointSID creates an AuthCheckPointSID for the session id
+func NewAuthCheckPointSID(sid string) AuthCheckPointSID {
+	return AuthCheckPointSID{sid: sid}
+}
+
+//SetAuth sets the X-chkp-sid header for an *http.Request
+func (c AuthCheckPointSID) SetAuth(req *http.Request) error {
+	req.Header.Set("X-chkp-sid", c.sid)
+	return nil
+}
+
+//LoginFunc logs into a service and returns the session token along
+//with how long it is valid for, or zero if the service does not say
+type LoginFunc func(ctx context.Context) (token string, ttl time.Duration, err error)
+
+//StaleRefresher is implemented by RefreshableAuthenticators shared by
+//concurrent requests. RefreshStale refreshes the credentials sent with
+//req unless another request refreshed them since, so that requests
+//rejected together share a single refresh.
+type StaleRefresher interface {
+	RefreshStale(ctx context.Context, req *http.Request) error
+}
+
+//AuthSession is a RefreshableAuthenticator for services that hand out
+//a session token on login. The token is obtained on first use, renewed
+//once its time to live has passed, and refreshed when a response with
+//status 401 or containing one of the expired messages is received.
+//It is safe for concurrent use, and concurrent requests needing a new
+//token share a single login.
+type AuthSession struct {
+	header  string
+	prefix  string
+	login   LoginFunc
+	expired []string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+//NewAuthSession creates an AuthSession that sets header to prefix
+//followed by the token obtained from login, e.g.
+//  NewAuthSession("Authorization", "AR-JWT ", login)
+//  NewAuthSession("X-chkp-sid", "", login, "generic_err_wrong_session_id")
+//The expired messages are case insensitive fragments of a response
+//body that mark the session as expired in addition to status 401.
+func NewAuthSession(header, prefix string, login LoginFunc, expired ...string) *AuthSession {
+	return &AuthSession{
+		header:  header,
+		prefix:  prefix,
+		login:   login,
+		expired: expired,
+	}
+}
+
+//SetAuth sets the session token on an *http.Request, logging in
+//first if there is no current token
+func (s *AuthSession) SetAuth(req *http.Request) error {
+	t, err := s.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set(s.header, s.prefix+t)
+	return nil
+}
+
+//Token returns the current session token, logging in first if there
+//is no current token
+func (s *AuthSession) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.token) > 0 && (s.expires.IsZero() || time.Now().Before(s.expires)) {
+		return s.token, nil
+	}
+	if err := s.refresh(ctx); err != nil {
+		return "", err
+	}
+	return s.token, nil
+}
+
+//Refresh logs in to obtain a new session token
+func (s *AuthSession) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refresh(ctx)
+}
+
+//RefreshStale logs in to obtain a new session token unless the token
+//sent with req was already replaced by a concurrent refresh
+func (s *AuthSession) RefreshStale(ctx context.Context, req *http.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.token) > 0 && req.Header.Get(s.header) != s.prefix+s.token &&
+		(s.expires.IsZero() || time.Now().Before(s.expires)) {
+		return nil
+	}
+	return s.refresh(ctx)
+}
+
+//refresh logs in, the caller must hold the lock
+func (s *AuthSession) refresh(ctx context.Context) error {
+	t, ttl, err := s.login(ctx)
+	if err != nil {
+		return err
+	}
+	if len(t) == 0 {
+		return fmt.Errorf("unable to obtain the session token")
+	}
+	s.token = t
+	s.expires = time.Time{}
+	if ttl > 0 {
+		s.expires = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+//Invalidate drops the current token so that the next request
+//logs in again
+func (s *AuthSession) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+//Current returns the current session token without logging in, ok
+//is false when there is none or it has expired
+func (s *AuthSession) Current() (token string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.token) > 0 && (s.expires.IsZero() || time.Now().Before(s.expires)) {
+		return s.token, true
+	}
+	return "", false
+}
+
+//Expired reports whether a response shows the session is no
+//longer valid
+func (s *AuthSession) Expired(code int, data []byte) bool {
+	if code == http.StatusUnauthorized {
+		return true
+	}
+	if code >= 200 && code < 300 {
+		return false
+	}
+	body := strings.ToLower(string(data))
+	for _, e := range s.expired {
+		if len(e) > 0 && strings.Contains(body, strings.ToLower(e)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAuthSessionRefresh(t *testing.T) {
+	//the server only accepts the second token handed out
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-sid") != "sid-2" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code": "generic_err_wrong_session_id"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	var logins int32
+	login := func(ctx context.Context) (string, time.Duration, error) {
+		n := atomic.AddInt32(&logins, 1)
+		return fmt.Sprintf("sid-%d", n), time.Minute, nil
+	}
+	auth := NewAuthSession("X-sid", "", login, "generic_err_wrong_session_id")
+
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(auth).
+		Method(GET).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if logins != 2 {
+		t.Fatalf("Expected 2 logins, got %d", logins)
+	}
+	//token is reused while current
+	if _, err = r.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if logins != 2 {
+		t.Fatalf("Expected token reuse, got %d logins", logins)
+	}
+}
+
+func TestAuthSessionRefreshOnce(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	login := func(ctx context.Context) (string, time.Duration, error) {
+		return "jwt", 0, nil
+	}
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(NewAuthSession("Authorization", "AR-JWT ", login)).
+		Method(GET).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); !IsUnauthorized(err) {
+		t.Fatalf("Expected unauthorized, got: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected a single resend, got %d calls", calls)
+	}
+}
+
+func TestAuthSessionRefreshShared(t *testing.T) {
+	//the server rejects the first token handed out
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-sid") == "sid-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code": "generic_err_wrong_session_id"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	var logins int32
+	login := func(ctx context.Context) (string, time.Duration, error) {
+		n := atomic.AddInt32(&logins, 1)
+		return fmt.Sprintf("sid-%d", n), time.Minute, nil
+	}
+	auth := NewAuthSession("X-sid", "", login, "generic_err_wrong_session_id")
+	if err := auth.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	//requests rejected together log in again once
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := NewRequestBuilder(ts.URL, getClient()).
+				Auth(auth).
+				Method(GET).
+				Build()
+			if err == nil {
+				_, err = r.Send()
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if logins != 2 {
+		t.Fatalf("Expected a single refresh, got %d logins", logins)
+	}
+}
+
+func TestAuthSessionExpires(t *testing.T) {
+	var logins int32
+	login := func(ctx context.Context) (string, time.Duration, error) {
+		atomic.AddInt32(&logins, 1)
+		return "jwt", time.Nanosecond, nil
+	}
+	auth := NewAuthSession("Authorization", "AR-JWT ", login)
+	req, _ := http.NewRequest("GET", "http://localhost", nil)
+	for i := 0; i < 2; i++ {
+		time.Sleep(time.Millisecond)
+		if err := auth.SetAuth(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if logins != 2 {
+		t.Fatalf("Expected login after expiry, got %d logins", logins)
+	}
+	if req.Header.Get("Authorization") != "AR-JWT jwt" {
+		t.Fatalf("Unexpected header: %s", req.Header.Get("Authorization"))
+	}
+	//an expired token is not current, and asking does not log in
+	time.Sleep(time.Millisecond)
+	if tok, ok := auth.Current(); ok || len(tok) > 0 || logins != 2 {
+		t.Fatalf("Expected no current token, got %q %v after %d logins", tok, ok, logins)
+	}
+}
+
+func TestStaticAuth(t *testing.T) {
+	tests := []struct {
+		auth   Authenticator
+		header string
+		value  string
+	}{
+		{NewAuthBasic("bob", "xxxxx"), "Authorization", "Basic Ym9iOnh4eHh4"},
+		{NewAuthBearer("tok"), "Authorization", "Bearer tok"},
+		{NewAuthAPIKey("X-API-Key", "k1"), "X-API-Key", "k1"},
+		{NewAuthARJWT("jwt"), "Authorization", "AR-JWT jwt"},
+		{NewAuthCheckPointSID("sid"), "X-chkp-sid", "sid"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "http://localhost", nil)
+		if err := tt.auth.SetAuth(req); err != nil {
+			t.Fatal(err)
+		}
+		if v := req.Header.Get(tt.header); v != tt.value {
+			t.Fatalf("%T: expected %s, got %s", tt.auth, tt.value, v)
+		}
+	}
+}
+
+func TestAuthHMAC(t *testing.T) {
+	secret := []byte("s3cret")
+	h := NewAuthHMAC("key1", secret)
+	h.now = func() time.Time { return time.Unix(1557328329, 0) }
+
+	req, _ := http.NewRequest("POST", "http://localhost/api/thing?x=1", bytes.NewBufferString(`{"a":1}`))
+	if err := h.SetAuth(req); err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte(`{"a":1}`))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("POST\n/api/thing?x=1\n1557328329\n" + hex.EncodeToString(sum[:]) + "\n"))
+	want := "HMAC key1:" + b64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+	if req.Header.Get("X-Timestamp") != "1557328329" {
+		t.Fatalf("Unexpected timestamp: %s", req.Header.Get("X-Timestamp"))
+	}
+}
+
+func TestAuthOAuth2(t *testing.T) {
+	var tokens int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		r.ParseForm()
+		if id != "client" || secret != "secret" || r.Form.Get("grant_type") != "client_credentials" ||
+			r.Form.Get("scope") != "read write" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(&tokens, 1)
+		fmt.Fprintf(w, `{"access_token": "at-%d", "token_type": "Bearer", "expires_in": 3600}`, n)
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	auth := NewAuthOAuth2(ts.URL+"/token", "client", "secret", getClient(), "read", "write")
+	for i := 0; i < 3; i++ {
+		r, err := NewRequestBuilder(ts.URL+"/api", getClient()).
+			Auth(auth).
+			Method(GET).
+			Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = r.Send(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tokens != 1 {
+		t.Fatalf("Expected cached token, got %d token requests", tokens)
+	}
+}
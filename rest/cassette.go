@@ -0,0 +1,293 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+//RecorderMode sets whether a Recorder replays or records exchanges
+type RecorderMode int
+
+const (
+	//ModeReplay answers requests from the cassette only, failing any
+	//request without a recorded match. No network access is made.
+	ModeReplay RecorderMode = iota
+	//ModeRecord sends every request and records the exchange
+	ModeRecord
+	//ModeReplayOrRecord replays when the cassette file exists and
+	//records a new one otherwise
+	ModeReplayOrRecord
+)
+
+//Cassette is the content of a cassette file, the recorded
+//exchanges in the order they took place
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+//Interaction is a single recorded request and response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+//RecordedRequest is a recorded http request
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+//RecordedResponse is a recorded http response
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+//Matcher reports whether a live request, with its body already
+//redacted, matches a recorded request
+type Matcher func(req *http.Request, body string, rec RecordedRequest) bool
+
+//DefaultMatcher matches on method, path, query and body. Json bodies
+//are compared by value so field order and spacing do not matter. The
+//host is ignored so a cassette can be replayed against any base url.
+func DefaultMatcher(req *http.Request, body string, rec RecordedRequest) bool {
+	if req.Method != rec.Method {
+		return false
+	}
+	u, err := req.URL.Parse(rec.URL)
+	if err != nil || u.Path != req.URL.Path || u.RawQuery != req.URL.RawQuery {
+		return false
+	}
+	if body == rec.Body {
+		return true
+	}
+	var a, b interface{}
+	if json.Unmarshal([]byte(body), &a) != nil || json.Unmarshal([]byte(rec.Body), &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+//redaction replaces matches of a pattern in recorded bodies
+type redaction struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+//Redacted is the placeholder for secrets removed from a cassette
+const Redacted = "REDACTED"
+
+//defaultRedactions remove passwords, session ids and tokens from
+//json and form bodies, and anything looking like a JWT
+var defaultRedactions = []redaction{
+	{regexp.MustCompile(`"(password|pass|sid|token|access_token|refresh_token|client_secret|api-key)"(\s*):(\s*)"[^"]*"`),
+		`"$1"$2:$3"` + Redacted + `"`},
+	{regexp.MustCompile(`(^|&)(password|client_secret)=[^&]*`), `$1$2=` + Redacted},
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), Redacted},
+}
+
+//defaultRedactHeaders are headers whose values are never recorded
+var defaultRedactHeaders = []string{
+	"Authorization",
+	"X-chkp-sid",
+	"Cookie",
+	"Set-Cookie",
+}
+
+//Recorder is an http.RoundTripper that records http exchanges to a
+//cassette file and replays them, so that client tests can run without
+//network access. Secrets are redacted from everything recorded.
+//
+//  rec, err := rest.NewRecorder("testdata/login.json", rest.ModeReplay, nil)
+//  client := &http.Client{Transport: rec}
+//  ...
+//  rec.Save() //when recording
+type Recorder struct {
+	//Matcher selects the recorded exchange for a request,
+	//defaults to DefaultMatcher
+	Matcher Matcher
+
+	path       string
+	mode       RecorderMode
+	real       http.RoundTripper
+	redactions []redaction
+	headers    []string
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+//NewRecorder creates a Recorder for the cassette file at path. When
+//recording, requests are sent using real, or http.DefaultTransport if
+//real is nil. When replaying the cassette file must exist.
+func NewRecorder(path string, mode RecorderMode, real http.RoundTripper) (*Recorder, error) {
+	if real == nil {
+		real = http.DefaultTransport
+	}
+	r := &Recorder{
+		Matcher:    DefaultMatcher,
+		path:       path,
+		mode:       mode,
+		real:       real,
+		redactions: append([]redaction(nil), defaultRedactions...),
+		headers:    append([]string(nil), defaultRedactHeaders...),
+	}
+
+	if r.mode == ModeReplayOrRecord {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+	if r.mode == ModeReplay {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read cassette file %s", path)
+		}
+		if err = json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("Failed to parse cassette file %s. %v", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+//Recording reports whether the recorder is recording
+func (r *Recorder) Recording() bool {
+	return r.mode == ModeRecord
+}
+
+//Redact adds a pattern whose matches in request and response bodies
+//are replaced by repl, which may refer to submatches as in
+//regexp.ReplaceAllString
+func (r *Recorder) Redact(re *regexp.Regexp, repl string) {
+	r.redactions = append(r.redactions, redaction{re, repl})
+}
+
+//RedactHeader adds a header whose value is never recorded
+func (r *Recorder) RedactHeader(key string) {
+	r.headers = append(r.headers, key)
+}
+
+//RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	rbody := r.redact(string(body))
+
+	if r.mode == ModeReplay {
+		return r.replay(req, rbody)
+	}
+
+	resp, err := r.real.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: r.redactHeader(req.Header),
+			Body:   rbody,
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     r.redactHeader(resp.Header),
+			Body:       r.redact(string(data)),
+		},
+	})
+	return resp, nil
+}
+
+//replay answers req with the first unused recorded exchange
+//that matches
+func (r *Recorder) replay(req *http.Request, body string) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || !r.Matcher(req, body, in.Request) {
+			continue
+		}
+		r.used[i] = true
+		h := in.Response.Header.Clone()
+		if h == nil {
+			h = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        h,
+			Body:          ioutil.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded interaction for [%s %s] in cassette %s", req.Method, req.URL, r.path)
+}
+
+//Save writes the recorded exchanges to the cassette file. It does
+//nothing when replaying.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, data, 0644)
+}
+
+//redact removes secrets from a body
+func (r *Recorder) redact(s string) string {
+	for _, rd := range r.redactions {
+		s = rd.re.ReplaceAllString(s, rd.repl)
+	}
+	return s
+}
+
+//redactHeader copies h with secret values replaced
+func (r *Recorder) redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	c := h.Clone()
+	for _, k := range r.headers {
+		if len(c.Values(k)) > 0 {
+			c.Set(k, Redacted)
+		}
+	}
+	return c
+}
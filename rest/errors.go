@@ -0,0 +1,105 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+//HTTPError is returned for a response the ErrorHandler considers
+//unsuccessful. Use errors.As to get at the details, or one of the
+//Is* helpers to test for common conditions.
+//
+//An ErrorHandler only needs to set the status code, body and any
+//service specific message or code; the request details and response
+//headers are filled in by Request.Send.
+type HTTPError struct {
+	//StatusCode is the http status code of the response
+	StatusCode int
+	//Method is the http method of the request
+	Method string
+	//URL is the url of the request
+	URL string
+	//Body is the raw response body
+	Body []byte
+	//Code is a service specific error code, if the service provides one
+	Code string
+	//Message is the error message parsed from the response body
+	Message string
+	//Header holds the response headers
+	Header http.Header
+}
+
+//Error implements error
+func (e *HTTPError) Error() string {
+	msg := e.Message
+	if len(msg) == 0 {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if len(e.Code) > 0 {
+		msg = fmt.Sprintf("%s : %s", e.Code, msg)
+	}
+	if len(e.URL) > 0 {
+		return fmt.Sprintf("%s %s: %d - %s", e.Method, e.URL, e.StatusCode, msg)
+	}
+	return fmt.Sprintf("%d - %s", e.StatusCode, msg)
+}
+
+//fill adds the request and response details not known
+//to the ErrorHandler
+func (e *HTTPError) fill(req *http.Request, resp *http.Response, code int, data []byte) {
+	if len(e.Method) == 0 {
+		e.Method = req.Method
+	}
+	if len(e.URL) == 0 {
+		e.URL = req.URL.String()
+	}
+	if e.StatusCode == 0 {
+		e.StatusCode = code
+	}
+	if e.Body == nil {
+		e.Body = data
+	}
+	if e.Header == nil && resp != nil {
+		e.Header = resp.Header
+	}
+}
+
+//StatusCode returns the http status code of err if it is
+//or wraps an *HTTPError, otherwise 0
+func StatusCode(err error) int {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.StatusCode
+	}
+	return 0
+}
+
+//IsNotFound reports whether err is an *HTTPError with status 404
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+//IsConflict reports whether err is an *HTTPError with status 409
+func IsConflict(err error) bool {
+	return StatusCode(err) == http.StatusConflict
+}
+
+//IsUnauthorized reports whether err is an *HTTPError with status 401
+func IsUnauthorized(err error) bool {
+	return StatusCode(err) == http.StatusUnauthorized
+}
+
+//IsRetryable reports whether err is a transient failure, either
+//an *HTTPError with status 429, 502, 503 or 504 or a transport error
+//such as a connection reset
+func IsRetryable(err error) bool {
+	switch StatusCode(err) {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return transient(err)
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPErrorFromSend(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Trace", "abc")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`not here`))
+	}))
+	defer ts.Close()
+
+	r, err := NewRequestBuilder(ts.URL+"/thing", getClient()).
+		Auth(AuthNoAuth{}).
+		Method(GET).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Send()
+
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		t.Fatalf("Expected *HTTPError, got: %v", err)
+	}
+	if he.StatusCode != 404 || he.Method != "GET" || he.URL != ts.URL+"/thing" {
+		t.Fatalf("Unexpected request details: %+v", he)
+	}
+	if string(he.Body) != "not here" {
+		t.Fatalf("Unexpected body: %s", he.Body)
+	}
+	if he.Header.Get("X-Trace") != "abc" {
+		t.Fatalf("Missing response header: %v", he.Header)
+	}
+	if !IsNotFound(err) || IsConflict(err) || IsUnauthorized(err) || IsRetryable(err) {
+		t.Fatalf("Unexpected classification for: %v", err)
+	}
+}
+
+func TestHTTPErrorHelpers(t *testing.T) {
+	wrap := func(code int) error {
+		return fmt.Errorf("wrapped: %w", &HTTPError{StatusCode: code})
+	}
+	if !IsConflict(wrap(409)) {
+		t.Fatal("Expected conflict")
+	}
+	if !IsUnauthorized(wrap(401)) {
+		t.Fatal("Expected unauthorized")
+	}
+	if !IsRetryable(wrap(503)) || IsRetryable(wrap(400)) {
+		t.Fatal("Unexpected retryable classification")
+	}
+	if IsNotFound(errors.New("404 not found")) {
+		t.Fatal("Plain errors are never not found")
+	}
+	e := &HTTPError{StatusCode: 404, Code: "Not Found", Message: "Widget Not Found"}
+	if e.Error() != "404 - Not Found : Widget Not Found" {
+		t.Fatalf("Unexpected message: %s", e.Error())
+	}
+}
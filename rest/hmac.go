@@ -0,0 +1,78 @@
+package rest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//AuthHMAC is an Authenticator that signs each *http.Request with
+//HMAC-SHA256. The string signed is the method, the request uri
+//(path and query), the X-Timestamp header value (unix seconds) and
+//the hex SHA-256 of the message body, each followed by a newline.
+//The signature is sent as
+//  Authorization: HMAC <key id>:<base64 signature>
+type AuthHMAC struct {
+	keyID  string
+	secret []byte
+	now    func() time.Time
+}
+
+//NewAuthHMAC creates an AuthHMAC signing with the secret
+//identified by keyID
+func NewAuthHMAC(keyID string, secret []byte) AuthHMAC {
+	return AuthHMAC{keyID: keyID, secret: secret, now: time.Now}
+}
+
+//SetAuth signs an *http.Request
+func (h AuthHMAC) SetAuth(req *http.Request) error {
+	if len(h.secret) == 0 {
+		return fmt.Errorf("hmac secret is not set")
+	}
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	now := time.Now
+	if h.now != nil {
+		now = h.now
+	}
+	ts := strconv.FormatInt(now().Unix(), 10)
+	req.Header.Set("X-Timestamp", ts)
+
+	sum := sha256.Sum256(body)
+	var sts strings.Builder
+	sts.WriteString(req.Method + "\n")
+	sts.WriteString(req.URL.RequestURI() + "\n")
+	sts.WriteString(ts + "\n")
+	sts.WriteString(hex.EncodeToString(sum[:]) + "\n")
+
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(sts.String()))
+	sig := b64.StdEncoding.EncodeToString(mac.Sum(nil))
+	req.Header.Set("Authorization", fmt.Sprintf("HMAC %s:%s", h.keyID, sig))
+	return nil
+}
+
+//readBody returns a copy of the request body without consuming it
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("unable to read request body for signing")
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
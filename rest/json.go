@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//JSONOption sets an option for decoding the response of
+//DoJSON and GetJSON
+type JSONOption func(*jsonOptions)
+
+type jsonOptions struct {
+	disallowUnknown bool
+	required        []string
+}
+
+//DisallowUnknownFields makes decoding fail if the response contains
+//fields that are not present in the result type
+func DisallowUnknownFields() JSONOption {
+	return func(o *jsonOptions) {
+		o.disallowUnknown = true
+	}
+}
+
+//RequireFields makes decoding fail if any of the given top level
+//fields (by json name) is missing from the response
+func RequireFields(fields ...string) JSONOption {
+	return func(o *jsonOptions) {
+		o.required = append(o.required, fields...)
+	}
+}
+
+//DoJSON marshals msg as the json message of the request built by b,
+//sends it bound to ctx, and decodes the response into a Resp.
+//The builder's ErrorHandler is applied as with Send. The full
+//Response is returned as well for access to the status and headers.
+//An empty response body leaves the result at its zero value.
+//
+//  host, _, err := rest.DoJSON[Host, Host](ctx, builder, host)
+func DoJSON[Req, Resp any](ctx context.Context, b *RequestableBuilder, msg Req, opts ...JSONOption) (Resp, *Response, error) {
+	var out Resp
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return out, nil, fmt.Errorf("Unable to create json message. %s", err)
+	}
+	b.Message(data)
+	return GetJSON[Resp](ctx, b, opts...)
+}
+
+//GetJSON sends the request built by b as is, bound to ctx, and decodes
+//the json response into a Resp. It suits requests without a message
+//such as a GET or DELETE; see DoJSON for the details.
+func GetJSON[Resp any](ctx context.Context, b *RequestableBuilder, opts ...JSONOption) (Resp, *Response, error) {
+	var out Resp
+	r, err := b.ContentType("application/json").
+		Header("Accept", "application/json").
+		Build()
+	if err != nil {
+		return out, nil, err
+	}
+	resp, err := r.DoContext(ctx)
+	if err != nil {
+		return out, nil, err
+	}
+	if err = decodeJSON(resp.Body, &out, opts...); err != nil {
+		return out, resp, err
+	}
+	return out, resp, nil
+}
+
+//decodeJSON decodes data into out applying the options
+func decodeJSON(data []byte, out interface{}, opts ...JSONOption) error {
+	var o jsonOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		if len(o.required) > 0 {
+			return fmt.Errorf("failed to transform response message. missing fields %s",
+				strings.Join(o.required, ", "))
+		}
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if o.disallowUnknown {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("failed to transform response message. %v", err)
+	}
+
+	if len(o.required) > 0 {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("failed to transform response message. %v", err)
+		}
+		var missing []string
+		for _, f := range o.required {
+			if _, ok := fields[f]; !ok {
+				missing = append(missing, f)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("failed to transform response message. missing fields %s",
+				strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type jsonThing struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+func jsonServer(t *testing.T, resp string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			t.Errorf("Unexpected accept: %s", r.Header.Get("Accept"))
+		}
+		if r.Method == "POST" {
+			var in jsonThing
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Name != "thing" {
+				t.Errorf("Unexpected message: %+v, %v", in, err)
+			}
+			if r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Unexpected content type: %s", r.Header.Get("Content-Type"))
+			}
+		}
+		w.Write([]byte(resp))
+	}))
+}
+
+func TestDoJSON(t *testing.T) {
+	ts := jsonServer(t, `{"id": "1", "name": "thing"}`)
+	defer ts.Close()
+
+	b := NewRequestBuilder(ts.URL, getClient()).Auth(AuthNoAuth{})
+	out, resp, err := DoJSON[jsonThing, jsonThing](context.Background(), b, jsonThing{Name: "thing"},
+		DisallowUnknownFields(), RequireFields("id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != "1" || resp.StatusCode != 200 {
+		t.Fatalf("Unexpected result: %+v, %d", out, resp.StatusCode)
+	}
+}
+
+func TestGetJSONStrict(t *testing.T) {
+	ts := jsonServer(t, `{"name": "thing", "extra": true}`)
+	defer ts.Close()
+
+	b := func() *RequestableBuilder {
+		return NewRequestBuilder(ts.URL, getClient()).Auth(AuthNoAuth{}).Method(GET)
+	}
+	if _, _, err := GetJSON[jsonThing](context.Background(), b()); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := GetJSON[jsonThing](context.Background(), b(), DisallowUnknownFields())
+	if err == nil || !strings.Contains(err.Error(), "extra") {
+		t.Fatalf("Expected unknown field error, got: %v", err)
+	}
+	_, _, err = GetJSON[jsonThing](context.Background(), b(), RequireFields("id", "name"))
+	if err == nil || !strings.Contains(err.Error(), "missing fields id") {
+		t.Fatalf("Expected missing field error, got: %v", err)
+	}
+}
+
+func TestGetJSONEmpty(t *testing.T) {
+	ts := jsonServer(t, ``)
+	defer ts.Close()
+
+	b := NewRequestBuilder(ts.URL, getClient()).Auth(AuthNoAuth{}).Method(GET)
+	out, _, err := GetJSON[jsonThing](context.Background(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != (jsonThing{}) {
+		t.Fatalf("Expected zero value, got: %+v", out)
+	}
+}
@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"log"
+	"net/http"
+)
+
+//Handler sends an *http.Request and returns the *http.Response,
+//as http.Client.Do does
+type Handler func(req *http.Request) (*http.Response, error)
+
+//Middleware wraps a Handler to add behaviour around sending a
+//request. A Middleware may inspect or change the outgoing request
+//before calling next, and inspect the response or error it returns.
+//The response body must be left readable for the Request.
+//
+//  func Audit(next rest.Handler) rest.Handler {
+//      return func(req *http.Request) (*http.Response, error) {
+//          resp, err := next(req)
+//          audit.Record(req.Method, req.URL, resp, err)
+//          return resp, err
+//      }
+//  }
+type Middleware func(next Handler) Handler
+
+//DefaultMiddleware is applied to every Request ahead of the
+//middleware added with RequestableBuilder.Use. By default it
+//logs the url of each request sent. It is read when a builder
+//is created so it should only be changed during initialization.
+var DefaultMiddleware = []Middleware{Logging(log.Printf)}
+
+//Logging returns a Middleware that logs the url of each request
+//sent using logf, e.g. log.Printf or testing.T.Logf
+func Logging(logf func(format string, v ...interface{})) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			logf("Rest send [%s]", req.URL)
+			return next(req)
+		}
+	}
+}
+
+//SetHeader returns a Middleware that sets a header key and value
+//on each request sent
+func SetHeader(key, value string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set(key, value)
+			return next(req)
+		}
+	}
+}
+
+//chain wraps h with the middleware so that the first middleware
+//is the outermost
+func chain(h Handler, mw []Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
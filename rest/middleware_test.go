@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Team") != "netops" {
+			t.Errorf("Expected injected header, got: %v", r.Header)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" before")
+				resp, err := next(req)
+				calls = append(calls, fmt.Sprintf("%s after %d", name, resp.StatusCode))
+				return resp, err
+			}
+		}
+	}
+	var logged []string
+	logf := func(format string, v ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, v...))
+	}
+
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Method(GET).
+		Use(record("outer"), SetHeader("X-Team", "netops"), Logging(logf), record("inner")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "outer before,inner before,inner after 200,outer after 200"
+	if got := strings.Join(calls, ","); got != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+	if len(logged) != 1 || logged[0] != fmt.Sprintf("Rest send [%s]", ts.URL) {
+		t.Fatalf("Unexpected log: %v", logged)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	deny := func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			return nil, fmt.Errorf("denied %s", req.URL.Path)
+		}
+	}
+	r, err := NewRequestBuilder("http://localhost:1/nowhere", getClient()).
+		Auth(AuthNoAuth{}).
+		Method(GET).
+		Use(deny).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err == nil || err.Error() != "denied /nowhere" {
+		t.Fatalf("Expected denied, got: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//AuthOAuth2 is a RefreshableAuthenticator using the OAuth2 client
+//credentials grant. The access token is cached until shortly before
+//it expires and is fetched again when a request is rejected with 401.
+type AuthOAuth2 struct {
+	*AuthSession
+	tokenURL string
+	id       string
+	secret   string
+	scopes   []string
+	c        *http.Client
+}
+
+//oauth2Token is the token endpoint response
+type oauth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+//NewAuthOAuth2 creates an AuthOAuth2 obtaining tokens from tokenURL
+//with the client id and secret, using client to call the token endpoint
+func NewAuthOAuth2(tokenURL, clientID, clientSecret string, client *http.Client, scopes ...string) *AuthOAuth2 {
+	o := &AuthOAuth2{
+		tokenURL: tokenURL,
+		id:       clientID,
+		secret:   clientSecret,
+		scopes:   scopes,
+		c:        client,
+	}
+	o.AuthSession = NewAuthSession("Authorization", "Bearer ", o.login)
+	return o
+}
+
+//login requests a new access token from the token endpoint
+func (o *AuthOAuth2) login(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(o.scopes) > 0 {
+		form.Set("scope", strings.Join(o.scopes, " "))
+	}
+
+	b := NewRequestBuilder(o.tokenURL, o.c).
+		Context(ctx).
+		Auth(NewAuthBasic(url.QueryEscape(o.id), url.QueryEscape(o.secret))).
+		ContentType("application/x-www-form-urlencoded").
+		Header("Accept", "application/json").
+		Message([]byte(form.Encode())).
+		Method(POST)
+	r, err := b.Build()
+	if err != nil {
+		return "", 0, err
+	}
+	data, err := r.SendContext(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var t oauth2Token
+	if err = json.Unmarshal(data, &t); err != nil {
+		return "", 0, fmt.Errorf("failed to transform token response. %v", err)
+	}
+	if len(t.TokenType) > 0 && !strings.EqualFold(t.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("unsupported token type [%s]", t.TokenType)
+	}
+
+	//renew a little ahead of expiry
+	ttl := time.Duration(t.ExpiresIn) * time.Second
+	if ttl > 30*time.Second {
+		ttl -= 10 * time.Second
+	}
+	return t.AccessToken, ttl, nil
+}
@@ -1,12 +1,18 @@
+//Package rest is the HTTP request layer shared by the Check Point,
+//Remedy and Widget clients: request building and sending, retries,
+//authentication, TLS, middleware and record/replay of exchanges.
 package rest
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 //HTTPMethod provides for the type of method to use
@@ -45,13 +51,17 @@ type ErrorHandler interface {
 type DefaultErrorHandler struct{}
 
 //Handle checks if there is a valid status code and returns
-//nil if the code is good otherwise an error
+//nil if the code is good otherwise an *HTTPError
 func (d DefaultErrorHandler) Handle(code int, data []byte) error {
 	//basically only check only validate the status code for 200/201
 	if code == 200 || code == 201 {
 		return nil
 	}
-	return fmt.Errorf("Error %d status code from request", code)
+	return &HTTPError{
+		StatusCode: code,
+		Body:       data,
+		Message:    fmt.Sprintf("Error %d status code from request", code),
+	}
 }
 
 //stuct used for building a Requestable object
@@ -63,6 +73,9 @@ type requestInit struct {
 	handler     ErrorHandler
 	msg         []byte
 	url         string
+	ctx         context.Context
+	retry       *RetryPolicy
+	middleware  []Middleware
 	c           *http.Client
 	r           *http.Request
 }
@@ -77,25 +90,122 @@ type RequestableBuilder struct {
 //Request is something that can be sent via rest. All
 //rest calls are made using it.
 type Request struct {
-	client  *http.Client
-	req     *http.Request
-	handler ErrorHandler
+	client     *http.Client
+	req        *http.Request
+	auth       Authenticator
+	headers    map[string]string
+	handler    ErrorHandler
+	retry      *RetryPolicy
+	middleware []Middleware
+}
+
+//Response is the result of a successful Request, giving access
+//to the status code and headers as well as the body
+type Response struct {
+	//StatusCode is the http status code of the response
+	StatusCode int
+	//Header holds the response headers
+	Header http.Header
+	//Body is the response message body
+	Body []byte
+	//Elapsed is the time taken by the call including any retries
+	Elapsed time.Duration
+	//Attempts is the number of times the request was sent
+	Attempts int
+	//Request is the http request that produced the response
+	Request *http.Request
+}
+
+//Location returns the Location header of the response, which
+//services commonly set to the url of a newly created resource
+func (r *Response) Location() string {
+	return r.Header.Get("Location")
 }
 
 //Send sends an http rest call, returning the response
 //as a byte array. An error is returned if there were
-//an issues with the request
+//an issues with the request. The request is bound to the
+//context given to the builder, if any.
 func (r *Request) Send() ([]byte, error) {
+	return r.SendContext(r.req.Context())
+}
 
-	log.Printf("Rest send [%s]", r.req.URL)
-	resp, err := r.client.Do(r.req)
+//SendContext sends an http rest call bound to ctx, returning
+//the response as a byte array. Cancelling ctx or passing its
+//deadline aborts the call and the context error is returned.
+func (r *Request) SendContext(ctx context.Context) ([]byte, error) {
+	resp, err := r.DoContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	//return the bytes
+	return resp.Body, nil
+}
 
-	//get the response stuff
-	code, data, err := parseResponse(resp)
-	//log.Printf("code %d\ndata: %s", code, string(data))
+//Do sends an http rest call, returning the full Response. An
+//error is returned if there were an issues with the request.
+//The request is bound to the context given to the builder, if any.
+func (r *Request) Do() (*Response, error) {
+	return r.DoContext(r.req.Context())
+}
+
+//DoContext sends an http rest call bound to ctx, returning
+//the full Response. Cancelling ctx or passing its deadline
+//aborts the call and the context error is returned.
+func (r *Request) DoContext(ctx context.Context) (*Response, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("nil context")
+	}
+
+	var (
+		code int
+		data []byte
+		req  *http.Request
+		resp *http.Response
+		err  error
+		i    int
+	)
+	start := time.Now()
+	refreshed := false
+	attempts := r.retry.attempts()
+	for i = 1; ; i++ {
+		code, data, req, resp, err = r.do(ctx)
+		//refresh expired credentials and send once more, this
+		//does not count as a retry
+		if ra, ok := r.auth.(RefreshableAuthenticator); ok &&
+			err == nil && !refreshed && ra.Expired(code, data) {
+			refreshed = true
+			log.Printf("Rest auth [%s] credentials expired, refreshing", r.req.URL)
+			if sr, ok := ra.(StaleRefresher); ok {
+				err = sr.RefreshStale(ctx, req)
+			} else {
+				err = ra.Refresh(ctx)
+			}
+			if err != nil {
+				return nil, err
+			}
+			i--
+			continue
+		}
+		if i >= attempts {
+			break
+		}
+		//stop unless the failure is transient per the retry policy
+		if err != nil && !r.retry.retryError(r.req.Method, err) {
+			break
+		}
+		if err == nil && !r.retry.retryResponse(r.req.Method, code, data) {
+			break
+		}
+		wait, ok := r.retry.backoff(i, resp)
+		if !ok || r.retry.MaxElapsed > 0 && time.Since(start)+wait > r.retry.MaxElapsed {
+			break
+		}
+		log.Printf("Rest retry [%s] attempt %d of %d in %s", r.req.URL, i+1, attempts, wait)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
 	//check for errors in response
 	if err != nil {
 		return nil, err
@@ -103,10 +213,58 @@ func (r *Request) Send() ([]byte, error) {
 	//deeper check for errors with provided error handler
 	if err = r.handler.Handle(code, data); err != nil {
 		//log.Printf("Http Send: code [%d], data [%s], Error [%v]", code, string(data), err)
+		var he *HTTPError
+		if errors.As(err, &he) {
+			he.fill(r.req, resp, code, data)
+		}
 		return nil, err
 	}
-	//return the bytes
-	return data, nil
+	return &Response{
+		StatusCode: code,
+		Header:     resp.Header,
+		Body:       data,
+		Elapsed:    time.Since(start),
+		Attempts:   i,
+		Request:    req,
+	}, nil
+}
+
+//do makes a single attempt of the request through the middleware
+//chain, rewinding the message body so the request can be sent more
+//than once
+func (r *Request) do(ctx context.Context) (int, []byte, *http.Request, *http.Response, error) {
+	req := r.req.Clone(ctx)
+	if r.req.GetBody != nil {
+		body, err := r.req.GetBody()
+		if err != nil {
+			return 0, nil, req, nil, err
+		}
+		req.Body = body
+	}
+
+	//set authentication for this attempt, then any explicit
+	//headers so they take precedence as they always have
+	if err := r.auth.SetAuth(req); err != nil {
+		return 0, nil, req, nil, err
+	}
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := chain(r.client.Do, r.middleware)(req)
+	if err != nil {
+		//prefer the context error so callers can test for
+		//context.Canceled / context.DeadlineExceeded
+		if ctx.Err() != nil {
+			return 0, nil, req, nil, ctx.Err()
+		}
+		return 0, nil, req, nil, err
+	}
+
+	//get the response stuff
+	code, data, err := parseResponse(resp)
+	//log.Printf("code %d\ndata: %s", code, string(data))
+	return code, data, req, resp, err
 }
 
 //NewRequestBuilder initializes a RequestableBuilder with required parameters.
@@ -118,6 +276,7 @@ func NewRequestBuilder(url string, client *http.Client) *RequestableBuilder {
 			c:           client,
 			contentType: "application/json",
 			method:      POST,
+			middleware:  append([]Middleware(nil), DefaultMiddleware...),
 		},
 	}
 }
@@ -134,14 +293,19 @@ func (b *RequestableBuilder) Build() (*Request, error) {
 		b.init.handler = DefaultErrorHandler{}
 	}
 
+	//default context if none
+	if b.init.ctx == nil {
+		b.init.ctx = context.Background()
+	}
+
 	//generate bare http request
-	r, err := http.NewRequest(b.init.method.String(), b.init.url, bytes.NewBuffer(b.init.msg))
+	r, err := http.NewRequestWithContext(b.init.ctx, b.init.method.String(), b.init.url, bytes.NewBuffer(b.init.msg))
 	if err != nil {
 		return nil, err
 	}
 
-	//set authentication
-	b.init.auth.SetAuth(r)
+	//authentication is set when sending so that expiring
+	//credentials are always current
 	b.init.r = r
 
 	//add content type
@@ -154,9 +318,13 @@ func (b *RequestableBuilder) Build() (*Request, error) {
 		}
 	}
 	return &Request{
-		client:  b.init.c,
-		req:     b.init.r,
-		handler: b.init.handler,
+		client:     b.init.c,
+		req:        b.init.r,
+		auth:       b.init.auth,
+		headers:    b.init.headers,
+		handler:    b.init.handler,
+		retry:      b.init.retry,
+		middleware: b.init.middleware,
 	}, nil
 }
 
@@ -210,6 +378,29 @@ func (b *RequestableBuilder) Message(msg []byte) *RequestableBuilder {
 	return b
 }
 
+//Context binds the request to ctx so that it may be cancelled or given
+//a deadline per call rather than relying only on the http.Client timeout.
+//If not called, context.Background() is used
+func (b *RequestableBuilder) Context(ctx context.Context) *RequestableBuilder {
+	b.init.ctx = ctx
+	return b
+}
+
+//Retry sets the RetryPolicy used when sending the request. If none
+//is provided the request is attempted only once
+func (b *RequestableBuilder) Retry(policy *RetryPolicy) *RequestableBuilder {
+	b.init.retry = policy
+	return b
+}
+
+//Use adds middleware to the request, after DefaultMiddleware and any
+//middleware added previously. The first middleware added is the
+//outermost, and the chain is run for every attempt when retrying.
+func (b *RequestableBuilder) Use(mw ...Middleware) *RequestableBuilder {
+	b.init.middleware = append(b.init.middleware, mw...)
+	return b
+}
+
 // validates the request has all its pieces and parts
 func (b *RequestableBuilder) validate() error {
 	//check for client
@@ -257,7 +448,7 @@ func parseResponse(response *http.Response) (code int, data []byte, err error) {
 		defer response.Body.Close()
 		data, err = ioutil.ReadAll(response.Body)
 		if err != nil {
-			return code, nil, fmt.Errorf("Unable to read response body")
+			return code, nil, fmt.Errorf("Unable to read response body: %w", err)
 		}
 		return code, data, nil
 	}
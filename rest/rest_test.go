@@ -0,0 +1,222 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func getClient() *http.Client {
+	trans := &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+	}
+	return &http.Client{
+		Transport: trans,
+		Timeout:   2 * time.Second,
+	}
+}
+func TestRequestable(t *testing.T) {
+
+	var msg = (`username=eroys&password=Remedy123!`)
+	var m = []byte(msg)
+
+	r, err := NewRequestBuilder("http://10.10.11.109:9090/api/jwt/login", cassetteClient(t, "remedy_login")).
+		//Auth(AuthBasic{user: "bob", pass: "xxxxx"}).
+		Auth(AuthNoAuth{}).
+		Message(m).
+		ContentType("application/x-www-form-urlencoded").
+		Method(POST).Build()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := r.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Content: %s", x)
+}
+
+func TestRequestableGet(t *testing.T) {
+
+	r, err := NewRequestBuilder("http://localhost:8080/api/widget/ID:621f812b-64a3-4c13-8f4d-295d3371a91d", cassetteClient(t, "widget_get")).
+		//Auth(AuthBasic{user: "bob", pass: "xxxxx"}).
+		Auth(AuthNoAuth{}).
+		ContentType("application/json").
+		Method(GET).Build()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := r.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Content: %s", x)
+}
+
+func TestRequestablePost(t *testing.T) {
+	var msg = (`{"name": "wigglyWidget", "size": "all over the place"}`)
+	var m = []byte(msg)
+
+	r, err := NewRequestBuilder("http://localhost:8080/api/widget/", cassetteClient(t, "widget_post")).
+		Auth(AuthNoAuth{}).
+		ContentType("application/json").
+		Message(m).
+		Method(POST).Build()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := r.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Content: %s", x)
+}
+
+func TestRequestableLogin(t *testing.T) {
+	var msg = (`{"user": "admin", "password": "vpn12345"}`)
+	var m = []byte(msg)
+
+	r, err := NewRequestBuilder("https://10.10.13.96/web_api/v1.4/login", cassetteClient(t, "checkpoint_login")).
+		Auth(AuthNoAuth{}).
+		ContentType("application/json").
+		Message(m).
+		Method(POST).Build()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := r.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Content: %s", x)
+}
+
+func TestSendContextCancel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Context(ctx).
+		Method(GET).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Send()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got: %v", err)
+	}
+}
+
+func TestSendContextOverride(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer ts.Close()
+
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Method(GET).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = r.SendContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected canceled, got: %v", err)
+	}
+	x, err := r.SendContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(x) != `{"ok": true}` {
+		t.Fatalf("Unexpected content: %s", x)
+	}
+}
+
+func TestDo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/api/widget/42")
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "42"}`))
+	}))
+	defer ts.Close()
+
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Message([]byte(`{"name": "do"}`)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := r.Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated || resp.Attempts != 1 {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+	if resp.Location() != "/api/widget/42" || resp.Header.Get("ETag") != `"v1"` {
+		t.Fatalf("Unexpected headers: %v", resp.Header)
+	}
+	if string(resp.Body) != `{"id": "42"}` {
+		t.Fatalf("Unexpected body: %s", resp.Body)
+	}
+	if resp.Request.Method != "POST" || resp.Request.URL.String() != ts.URL {
+		t.Fatalf("Unexpected request echo: %s %s", resp.Request.Method, resp.Request.URL)
+	}
+}
+
+// func TestRequest(t *testing.T) {
+// 	r := Request{
+// 		Headers: map[string]string{
+// 			"h1": "h1V",
+// 			"h2": "h2v",
+// 		},
+
+// 		ContentType: "application/json",
+// 		Method:      POST,
+// 		URL:         "http://localhost/something",
+// 		Auth:        AuthNoAuth{},
+// 	}
+// 	err := r.Build()
+
+// 	if err != nil {
+// 		t.Fatal(err)
+// 	}
+// 	t.Log("Validation Successful")
+
+// 	t.Logf("Method %s\n", r.Method)
+// 	t.Logf("Headers: %v", r.Headers)
+
+// 	rg := RequestGet{
+// 		R: Request{
+// 			Headers: map[string]string{
+// 				"h1": "h1V",
+// 				"h2": "h2v",
+// 			},
+
+// 			ContentType: "application/json",
+// 			Method:      POST,
+// 			URL:         "http://localhost/something",
+// 			Auth:        AuthNoAuth{},
+// 		},
+// 	}
+// }
@@ -0,0 +1,225 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+//RetryPolicy determines if and when a failed request is sent
+//again. A Request without a RetryPolicy is sent exactly once.
+//
+//Responses with 429 Too Many Requests or 503 Service Unavailable,
+//or matching RetryBody, are retried for any method as the server
+//rejected the request. Other statuses, such as a 502 or 504 from a
+//proxy whose upstream may have applied the request, and transport
+//errors are only retried for idempotent methods. A transport error
+//is also retried when the connection could not be made and the
+//request never reached the server. This way e.g. a POST timing out
+//is not applied twice. A refused connection is not retried.
+type RetryPolicy struct {
+	//MaxAttempts is the total number of attempts including the
+	//first one. Values less than 2 disable retries
+	MaxAttempts int
+	//MinBackoff is the wait before the first retry. Each following
+	//retry doubles the wait up to MaxBackoff
+	MinBackoff time.Duration
+	//MaxBackoff caps the wait between attempts. A response asking
+	//for a longer wait with Retry-After is not retried.
+	MaxBackoff time.Duration
+	//MaxElapsed limits the time spent retrying, a retry that would
+	//start later than MaxElapsed after the first attempt is not
+	//made. Zero does not limit it.
+	MaxElapsed time.Duration
+	//Jitter randomizes each wait between half and the full backoff
+	//so that many clients do not retry in lock step
+	Jitter bool
+	//RetryStatus lists the http status codes that are retried
+	RetryStatus []int
+	//RetryBody lists case insensitive fragments of a response body
+	//that mark the response as retryable regardless of status code,
+	//e.g. a service reporting that the session is busy
+	RetryBody []string
+}
+
+//NewRetryPolicy returns a RetryPolicy allowing maxRetries retries after
+//the first attempt with exponential backoff and jitter, for up to a
+//minute, retrying on connection failures and 429, 502, 503 and 504
+//responses
+func NewRetryPolicy(maxRetries int) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: maxRetries + 1,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+		MaxElapsed:  time.Minute,
+		Jitter:      true,
+		RetryStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+//attempts returns the number of attempts allowed by the policy,
+//a nil policy allows only one
+func (p *RetryPolicy) attempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+//retryResponse checks if a response to a request with method
+//should be retried based on the status code and the body content
+func (p *RetryPolicy) retryResponse(method string, code int, data []byte) bool {
+	if p == nil {
+		return false
+	}
+	for _, c := range p.RetryStatus {
+		if c == code {
+			return rejected(code) || idempotent(method)
+		}
+	}
+	if code >= 200 && code < 300 {
+		return false
+	}
+	if len(p.RetryBody) > 0 && len(data) > 0 {
+		body := strings.ToLower(string(data))
+		for _, b := range p.RetryBody {
+			if len(b) > 0 && strings.Contains(body, strings.ToLower(b)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//retryError checks if a transport error for a request with method
+//is transient and the request safe to send again. Context errors
+//are never retried.
+func (p *RetryPolicy) retryError(method string, err error) bool {
+	if p == nil || !transient(err) {
+		return false
+	}
+	return idempotent(method) || unsent(err)
+}
+
+//rejected checks if a response with code reports the request was
+//not applied, so that it is safe to send again with any method
+func rejected(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+//idempotent checks if requests with method may be applied more
+//than once
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+//unsent checks if err is a failure to connect, so the request was
+//never sent to the server
+func unsent(err error) bool {
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
+
+//transient checks if err is a connection level failure that
+//may succeed when tried again. A refused connection is not, the
+//service is down rather than busy.
+func transient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return false
+	}
+	if unsent(err) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return false
+}
+
+//backoff returns the wait before the given retry (starting with 1).
+//A Retry-After value from the previous response takes precedence
+//over the computed backoff, ok is false if it is longer than
+//MaxBackoff and the request is not to be retried.
+func (p *RetryPolicy) backoff(retry int, resp *http.Response) (d time.Duration, ok bool) {
+	if d, ok := retryAfter(resp); ok {
+		return d, p.MaxBackoff <= 0 || d <= p.MaxBackoff
+	}
+	d = p.MinBackoff
+	for i := 1; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter && d > 1 {
+		half := d / 2
+		d = half + time.Duration(rand.Int63n(int64(d-half)))
+	}
+	return d, true
+}
+
+//retryAfter parses the Retry-After header in either the delay
+//seconds or http date form
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if len(v) == 0 {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+//sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
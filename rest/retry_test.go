@@ -0,0 +1,302 @@
+package rest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func retryPolicy(maxRetries int) *RetryPolicy {
+	p := NewRetryPolicy(maxRetries)
+	p.MinBackoff = time.Millisecond
+	p.MaxBackoff = 5 * time.Millisecond
+	return p
+}
+
+func TestRetryRewindsBody(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) != `{"name": "retry"}` {
+			t.Errorf("Unexpected body on attempt %d: %s", calls+1, b)
+		}
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Message([]byte(`{"name": "retry"}`)).
+		Retry(retryPolicy(3)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Method(GET).
+		Retry(retryPolicy(2)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err == nil {
+		t.Fatal("Expected error, got none")
+	}
+	if calls != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRetryNoPolicy(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Method(GET).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err == nil {
+		t.Fatal("Expected error, got none")
+	}
+	if calls != 1 {
+		t.Fatalf("Expected 1 attempt, got %d", calls)
+	}
+}
+
+func TestRetryBody(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message": "Session is BUSY, try again"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	p := retryPolicy(1)
+	p.RetryBody = []string{"session is busy"}
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Method(GET).
+		Retry(p).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", calls)
+	}
+}
+
+func TestRetryConnectionReset(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			//drop the connection without a response
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Method(GET).
+		Retry(retryPolicy(1)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	p := retryPolicy(1)
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", "7")
+	//a longer wait than MaxBackoff is not retried
+	if d, ok := p.backoff(1, resp); ok {
+		t.Fatalf("Expected no retry, got %s", d)
+	}
+	p.MaxBackoff = 10 * time.Second
+	if d, ok := p.backoff(1, resp); !ok || d != 7*time.Second {
+		t.Fatalf("Expected 7s, got %s", d)
+	}
+	p = retryPolicy(1)
+	if d, _ := p.backoff(4, nil); d > p.MaxBackoff || d < p.MaxBackoff/2 {
+		t.Fatalf("Backoff %s out of range", d)
+	}
+}
+
+func TestRetryPostConnectionReset(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		//the request may have been applied, so it is not resent
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer ts.Close()
+
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Method(POST).
+		Message([]byte(`{}`)).
+		Retry(retryPolicy(2)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err == nil {
+		t.Fatal("Expected the connection reset to fail the request")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("Expected a single attempt, got %d", n)
+	}
+}
+
+func TestRetryPostBadGateway(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		//the upstream may have applied the request
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Method(POST).
+		Message([]byte(`{}`)).
+		Retry(retryPolicy(2)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err == nil {
+		t.Fatal("Expected the bad gateway to fail the request")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("Expected a single attempt, got %d", n)
+	}
+}
+
+func TestRetryConnectionRefused(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	p := retryPolicy(5)
+	p.MinBackoff, p.MaxBackoff = time.Second, time.Second
+	r, err := NewRequestBuilder(url, getClient()).
+		Auth(AuthNoAuth{}).
+		Method(GET).
+		Retry(p).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err = r.Send(); err == nil {
+		t.Fatal("Expected the refused connection to fail the request")
+	}
+	if d := time.Since(start); d >= time.Second {
+		t.Fatalf("Expected no retry of a refused connection, took %s", d)
+	}
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	p := retryPolicy(100)
+	p.MinBackoff, p.MaxBackoff, p.Jitter = 10*time.Millisecond, 10*time.Millisecond, false
+	p.MaxElapsed = 25 * time.Millisecond
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Method(GET).
+		Retry(p).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	if n := atomic.LoadInt32(&calls); n < 2 || n > 3 {
+		t.Fatalf("Expected retries to stop after MaxElapsed, got %d attempts", n)
+	}
+}
+
+func TestRetryCancelledWait(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	p := retryPolicy(1)
+	p.MaxBackoff = time.Minute
+	r, err := NewRequestBuilder(ts.URL, getClient()).
+		Auth(AuthNoAuth{}).
+		Method(GET).
+		Context(ctx).
+		Retry(p).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Send(); err != context.DeadlineExceeded {
+		t.Fatalf("Expected deadline exceeded, got: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	b64 "encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+//TLSOptions configures TLS for the *http.Client used by an API
+//client. The zero value uses the system roots and TLS 1.2 or later.
+type TLSOptions struct {
+	//CAFiles are paths of PEM files with certificates trusted in
+	//addition to the system roots
+	CAFiles []string
+	//CAPEM holds PEM certificates trusted in addition to the
+	//system roots
+	CAPEM []byte
+	//NoSystemRoots trusts only CAFiles and CAPEM
+	NoSystemRoots bool
+
+	//CertFile and KeyFile are paths of the PEM client certificate
+	//and key presented for mutual TLS
+	CertFile string
+	KeyFile  string
+	//CertPEM and KeyPEM hold the PEM client certificate and key,
+	//as an alternative to CertFile and KeyFile
+	CertPEM []byte
+	KeyPEM  []byte
+
+	//ServerName overrides the name used to verify the server
+	//certificate, e.g. when connecting by ip address
+	ServerName string
+	//MinVersion is the minimum TLS version, e.g. tls.VersionTLS13.
+	//Defaults to tls.VersionTLS12
+	MinVersion uint16
+	//InsecureSkipVerify accepts any server certificate. Only for
+	//test systems with self signed certificates
+	InsecureSkipVerify bool
+	//PinnedSPKI lists base64 SHA-256 hashes of the subject public
+	//key info of certificates to accept. When set, the connection
+	//fails unless a certificate of the verified chain matches, e.g.
+	//the server's or its CA's. With InsecureSkipVerify there is no
+	//verified chain and only the server's own certificate is
+	//matched.
+	PinnedSPKI []string
+}
+
+//Config builds a *tls.Config from the options
+func (o TLSOptions) Config() (*tls.Config, error) {
+	c := &tls.Config{
+		ServerName:         o.ServerName,
+		MinVersion:         o.MinVersion,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+	if c.MinVersion == 0 {
+		c.MinVersion = tls.VersionTLS12
+	}
+
+	//trusted roots
+	if len(o.CAFiles) > 0 || len(o.CAPEM) > 0 || o.NoSystemRoots {
+		var pool *x509.CertPool
+		if !o.NoSystemRoots {
+			pool, _ = x509.SystemCertPool()
+		}
+		if pool == nil {
+			pool = x509.NewCertPool()
+		}
+		for _, f := range o.CAFiles {
+			cert, err := ioutil.ReadFile(f)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read certificate file %s", f)
+			}
+			if !pool.AppendCertsFromPEM(cert) {
+				return nil, fmt.Errorf("No certificates found in file %s", f)
+			}
+		}
+		if len(o.CAPEM) > 0 && !pool.AppendCertsFromPEM(o.CAPEM) {
+			return nil, fmt.Errorf("No certificates found in CA PEM")
+		}
+		c.RootCAs = pool
+	}
+
+	//client certificate for mutual TLS
+	var (
+		cert tls.Certificate
+		err  error
+	)
+	switch {
+	case len(o.CertFile) > 0 || len(o.KeyFile) > 0:
+		cert, err = tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load client certificate. %v", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	case len(o.CertPEM) > 0 || len(o.KeyPEM) > 0:
+		cert, err = tls.X509KeyPair(o.CertPEM, o.KeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load client certificate. %v", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	//certificate pinning
+	if len(o.PinnedSPKI) > 0 {
+		pins := make(map[string]bool, len(o.PinnedSPKI))
+		for _, p := range o.PinnedSPKI {
+			pins[p] = true
+		}
+		insecure := o.InsecureSkipVerify
+		c.VerifyConnection = func(cs tls.ConnectionState) error {
+			//the peer certificates are as sent by the server, only
+			//its own certificate can be trusted without verification
+			if insecure {
+				if len(cs.PeerCertificates) > 0 && pins[SPKIHash(cs.PeerCertificates[0])] {
+					return nil
+				}
+				return fmt.Errorf("server certificate does not match a pinned key")
+			}
+			for _, chain := range cs.VerifiedChains {
+				for _, vc := range chain {
+					if pins[SPKIHash(vc)] {
+						return nil
+					}
+				}
+			}
+			return fmt.Errorf("server certificate does not match a pinned key")
+		}
+	}
+	return c, nil
+}
+
+//Transport returns an *http.Transport configured with the options
+//and the connection pool settings used by the API clients
+func (o TLSOptions) Transport() (*http.Transport, error) {
+	c, err := o.Config()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		TLSClientConfig:     c,
+	}, nil
+}
+
+//SPKIHash returns the base64 SHA-256 hash of the certificate's
+//subject public key info, as used for TLSOptions.PinnedSPKI
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return b64.StdEncoding.EncodeToString(sum[:])
+}
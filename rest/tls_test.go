@@ -0,0 +1,175 @@
+package rest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+//newCert creates a certificate signed by parent (self signed if nil)
+//returning the certificate, its key and the PEM encoding of both
+func newCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	kder, _ := x509.MarshalECPrivateKey(key)
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder})
+}
+
+func tlsGet(t *testing.T, url string, o TLSOptions) error {
+	trans, err := o.Transport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRequestBuilder(url, &http.Client{Transport: trans, Timeout: 2 * time.Second}).
+		Auth(AuthNoAuth{}).
+		Method(GET).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Send()
+	return err
+}
+
+func TestTLSOptionsCA(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	srvPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+
+	if err := tlsGet(t, ts.URL, TLSOptions{}); err == nil {
+		t.Fatal("Expected unknown authority error, got none")
+	}
+	if err := tlsGet(t, ts.URL, TLSOptions{CAPEM: srvPEM}); err != nil {
+		t.Fatal(err)
+	}
+	f := filepath.Join(t.TempDir(), "ca.pem")
+	ioutil.WriteFile(f, srvPEM, 0600)
+	if err := tlsGet(t, ts.URL, TLSOptions{CAFiles: []string{f}, NoSystemRoots: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tlsGet(t, ts.URL, TLSOptions{InsecureSkipVerify: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (TLSOptions{CAFiles: []string{f + ".missing"}}).Config(); err == nil {
+		t.Fatal("Expected missing file error, got none")
+	}
+}
+
+func TestTLSOptionsPinning(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	pin := SPKIHash(ts.Certificate())
+	if err := tlsGet(t, ts.URL, TLSOptions{InsecureSkipVerify: true, PinnedSPKI: []string{pin}}); err != nil {
+		t.Fatal(err)
+	}
+	err := tlsGet(t, ts.URL, TLSOptions{InsecureSkipVerify: true, PinnedSPKI: []string{"bm90IHRoZSBwaW4="}})
+	if err == nil {
+		t.Fatal("Expected pin mismatch, got none")
+	}
+}
+
+func TestTLSOptionsPinnedChain(t *testing.T) {
+	pinned, _, pinnedPEM, _ := newCert(t, "pinned ca", nil, nil)
+	other, otherKey, otherPEM, _ := newCert(t, "other ca", nil, nil)
+	leaf, leafKey, _, _ := newCert(t, "server", other, otherKey)
+
+	//an unpinned server certificate sent with the pinned ca
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{{
+		Certificate: [][]byte{leaf.Raw, pinned.Raw},
+		PrivateKey:  leafKey,
+	}}}
+	ts.StartTLS()
+	defer ts.Close()
+
+	pin := []string{SPKIHash(pinned)}
+	if err := tlsGet(t, ts.URL, TLSOptions{InsecureSkipVerify: true, PinnedSPKI: pin}); err == nil {
+		t.Fatal("Expected pin mismatch for the unverified chain, got none")
+	}
+	roots := append(append([]byte(nil), pinnedPEM...), otherPEM...)
+	o := TLSOptions{CAPEM: roots, NoSystemRoots: true, PinnedSPKI: pin}
+	if err := tlsGet(t, ts.URL, o); err == nil {
+		t.Fatal("Expected pin mismatch for a ca outside the verified chain, got none")
+	}
+	o.PinnedSPKI = []string{SPKIHash(other)}
+	if err := tlsGet(t, ts.URL, o); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSOptionsMutual(t *testing.T) {
+	ca, caKey, caPEM, _ := newCert(t, "test ca", nil, nil)
+	_, _, certPEM, keyPEM := newCert(t, "test client", ca, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caPEM)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "test client" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	ts.StartTLS()
+	defer ts.Close()
+
+	if err := tlsGet(t, ts.URL, TLSOptions{InsecureSkipVerify: true}); err == nil {
+		t.Fatal("Expected handshake failure without client certificate")
+	}
+	o := TLSOptions{InsecureSkipVerify: true, CertPEM: certPEM, KeyPEM: keyPEM}
+	if err := tlsGet(t, ts.URL, o); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	o = TLSOptions{
+		InsecureSkipVerify: true,
+		CertFile:           filepath.Join(dir, "client.pem"),
+		KeyFile:            filepath.Join(dir, "client.key"),
+	}
+	ioutil.WriteFile(o.CertFile, certPEM, 0600)
+	ioutil.WriteFile(o.KeyFile, keyPEM, 0600)
+	if err := tlsGet(t, ts.URL, o); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(o.KeyFile)
+	if _, err := o.Config(); err == nil {
+		t.Fatal("Expected missing key error, got none")
+	}
+}
@@ -1,20 +1,27 @@
 package client
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
+	"path"
+	"strings"
 	"time"
+
+	"github.com/ericroys/rest"
 )
 
 //Client a client for the Widget API
 type Client struct {
 	svcurl     string
 	httpClient *http.Client
+	auth       rest.Authenticator
+	retry      *rest.RetryPolicy
+	middleware []rest.Middleware
 }
 
 //NewClient returns a new initialized Client stucture
@@ -32,6 +39,7 @@ func NewClient(baseurl string) (*Client, error) {
 
 	return &Client{
 		svcurl: baseurl,
+		auth:   rest.AuthNoAuth{},
 		httpClient: &http.Client{
 			Transport: trans,
 			Timeout:   2 * time.Second,
@@ -39,14 +47,55 @@ func NewClient(baseurl string) (*Client, error) {
 	}, nil
 }
 
+//SetMaxRetries sets the number of times a failed request is retried
+//using exponential backoff. Zero disables retries.
+func (c *Client) SetMaxRetries(retries int) {
+	if retries <= 0 {
+		c.retry = nil
+		return
+	}
+	c.retry = rest.NewRetryPolicy(retries)
+}
+
+//SetTLSOptions configures TLS for requests to the Widget API, such as
+//trusted CAs and a client certificate for mutual TLS
+func (c *Client) SetTLSOptions(opts rest.TLSOptions) error {
+	trans, err := opts.Transport()
+	if err != nil {
+		return err
+	}
+	c.httpClient.Transport = trans
+	return nil
+}
+
+//SetTransport sets the http.RoundTripper used to send requests, e.g. a
+//rest.Recorder replaying recorded exchanges in tests
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
+//SetAuthenticator sets the authentication used for requests to the
+//Widget API, e.g. rest.NewAuthBasic(user, pass). Defaults to none.
+func (c *Client) SetAuthenticator(auth rest.Authenticator) {
+	if auth == nil {
+		auth = rest.AuthNoAuth{}
+	}
+	c.auth = auth
+}
+
+//Use adds middleware to every request sent by the client,
+//e.g. for logging, metrics or header injection
+func (c *Client) Use(mw ...rest.Middleware) {
+	c.middleware = append(c.middleware, mw...)
+}
+
 //UpdateWidget updates a Widget by id, with values received in WidgetNew.
 //If the widget is not found, and error will indicate so
 func (c *Client) UpdateWidget(id string, w WidgetNew) (Widget, error) {
 	fmt.Println("WidgetUpdate - Start")
 	//Define the Widget as the transformer to use for
 	//the unmarshall and what we return to client
-	var r Widget
-	err := c.update("widget", id, w, &r)
+	r, err := update[WidgetNew, Widget](c, "widget", id, w)
 
 	if err != nil {
 		return r, err
@@ -62,8 +111,7 @@ func (c *Client) CreateWidget(w WidgetNew) (Widget, error) {
 	fmt.Println("WidgetCreate -Start")
 	//Define the Widget as the transformer to use for
 	//the unmarshall and what we return to client
-	var r Widget
-	err := c.create("widget", w, &r)
+	r, err := create[WidgetNew, Widget](c, "widget", w)
 
 	if err != nil {
 		return r, err
@@ -77,8 +125,7 @@ func (c *Client) CreateWidget(w WidgetNew) (Widget, error) {
 //found an error is returned
 func (c *Client) GetWidget(id string) (Widget, error) {
 	fmt.Println("WidgetGet -Starts")
-	var r Widget
-	err := c.get("widget", id, &r)
+	r, err := get[Widget](c, "widget", id)
 	if err != nil {
 		return r, err
 	}
@@ -94,35 +141,21 @@ func (c *Client) DeleteWidget(id string) error {
 	return err
 }
 
-func (c *Client) update(uri, id string, msg, trans interface{}) error {
-	//build message
-	toMsg, err := getMessage(msg)
-	if err != nil {
-		return err
-	}
-
+//update is generalized rest update function that takes in the uri for the service,
+//an object id and a message (must be marshallable to json), and returns the
+//response body transformed to a Resp.
+func update[Req, Resp any](c *Client, uri, id string, msg Req) (Resp, error) {
+	var r Resp
 	//build url
 	url, err := c.getPath(uri, id)
 	if err != nil {
-		return err
+		return r, err
 	}
 
-	//setup the request
-	request, _ := http.NewRequest("POST", url, bytes.NewBuffer(toMsg))
-	request.Header.Add("Content-Type", "application/json")
-
-	//send the request
-	data, err := c.send(request)
-	if err != nil {
-		return err
-	}
-	//convert return message
-	err = json.Unmarshal([]byte(data), &trans)
-	log.Printf("Unmarshal Trans [%v], Error [%v]", trans, err)
-	if err != nil {
-		return err
-	}
-	return nil
+	//send the request and convert return message
+	r, _, err = rest.DoJSON[Req, Resp](context.Background(), c.getBuilder(url, rest.POST), msg)
+	log.Printf("Unmarshal Trans [%v], Error [%v]", r, err)
+	return r, err
 }
 
 //delete is generalized rest delete function that takes in a uri and an object id
@@ -135,73 +168,54 @@ func (c *Client) delete(uri string, id string) error {
 	}
 
 	//setup the request
-	request, _ := http.NewRequest("DELETE", url, nil)
-	request.Header.Add("Content-Type", "application/json")
+	request, err := c.getBuilder(url, rest.DELETE).Build()
+	if err != nil {
+		return err
+	}
 	_, er := c.send(request)
 
 	return er
 }
 
-//get is generalized rest get function that takes in uri, object id, and transformation
-//interface for the response.
-func (c *Client) get(uri string, id string, trans interface{}) error {
+//get is generalized rest get function that takes in uri and object id, and
+//returns the response body transformed to a Resp.
+func get[Resp any](c *Client, uri string, id string) (Resp, error) {
+	var r Resp
 	//build url
 	url, err := c.getPath(uri, id)
 	if err != nil {
-		return err
-	}
-
-	//setup the request
-	request, _ := http.NewRequest("GET", url, nil)
-	request.Header.Add("Content-Type", "application/json")
-
-	//send the request
-	data, err := c.send(request)
-	if err != nil {
-		return err
-	}
-	//convert return message
-	err = json.Unmarshal([]byte(data), &trans)
-	log.Printf("Unmarshal Trans [%v], Error [%v]", trans, err)
-	if err != nil {
-		return err
+		return r, err
 	}
-	return nil
 
+	//send the request and convert return message
+	r, _, err = rest.GetJSON[Resp](context.Background(), c.getBuilder(url, rest.GET))
+	log.Printf("Unmarshal Trans [%v], Error [%v]", r, err)
+	return r, err
 }
 
-//create is generalized rest create function that takes in the uri for the service,
-//a message interface (must be marshallable to json), and an interface for transforming
-//the response body to applicable format.
-func (c *Client) create(uri string, msg interface{}, trans interface{}) error {
-	//build message
-	toMsg, err := getMessage(msg)
-	if err != nil {
-		return err
-	}
-
+//create is generalized rest create function that takes in the uri for the service
+//and a message (must be marshallable to json), and returns the response body
+//transformed to a Resp.
+func create[Req, Resp any](c *Client, uri string, msg Req) (Resp, error) {
+	var r Resp
 	//build url
 	url, err := c.getPath(uri, "")
 	if err != nil {
-		return err
+		return r, err
 	}
 
-	//setup the request
-	request, _ := http.NewRequest("POST", url, bytes.NewBuffer(toMsg))
-	request.Header.Add("Content-Type", "application/json")
-
-	//send the request
-	data, err := c.send(request)
+	//send the request and convert return message
+	r, resp, err := rest.DoJSON[Req, Resp](context.Background(), c.getBuilder(url, rest.POST), msg)
 	if err != nil {
-		return err
+		return r, err
 	}
-	//convert return message
-	err = json.Unmarshal([]byte(data), &trans)
-	log.Printf("Unmarshal Trans [%v], Error [%v]", trans, err)
-	if err != nil {
-		return err
+	//a service may answer a create with only the location
+	//of the new object, in which case fetch it from there
+	if len(resp.Body) == 0 && len(resp.Location()) > 0 {
+		return get[Resp](c, uri, path.Base(resp.Location()))
 	}
-	return nil
+	log.Printf("Unmarshal Trans [%v], Error [%v]", r, err)
+	return r, nil
 }
 
 //getPath returns full url for a request
@@ -226,44 +240,29 @@ func (c *Client) getPath(uri string, id string) (string, error) {
 	return rurl, nil
 }
 
-//getMessage accepts an interface to convert
-//to []byte to be used as message sent to server
-func getMessage(i interface{}) ([]byte, error) {
-
-	t, err := json.Marshal(&i)
-	if err != nil {
-		return nil, fmt.Errorf("Unable to create json message. %s", err)
-	}
-	if t == nil || len(t) < 1 {
-		return nil, fmt.Errorf("Unable to create json message. No message content")
-	}
-	return t, nil
+//getBuilder returns a rest.RequestableBuilder for the Widget API
+//with the client's retry policy and error handling
+func (c *Client) getBuilder(url string, method rest.HTTPMethod) *rest.RequestableBuilder {
+	return rest.NewRequestBuilder(url, c.httpClient).
+		Auth(c.auth).
+		ContentType("application/json").
+		Method(method).
+		Retry(c.retry).
+		Use(c.middleware...).
+		ErrorHandler(errHandler{})
 }
 
-//send sends an *http.Request and returns a byte array and error
-//The byte array will be nil if there is a non nil error
-
-func (c *Client) send(req *http.Request) ([]byte, error) {
+//send sends a rest.Request and returns the rest.Response and error
+//The response will be nil if there is a non nil error
+func (c *Client) send(req *rest.Request) (*rest.Response, error) {
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	//get the response stuff
-	code, data, err := parseResponse(resp)
-	log.Printf("code [%d], data [%d], Error [%v]", code, len(data), err)
-	//check for errors in response
-	if err != nil {
-		return nil, err
-	}
-	//check for errors using response code and contents of resp.body
-	err = handleError(code, data)
-	log.Printf("Http Send: code [%d], data [%d], Error [%v]", code, len(data), err)
+	resp, err := req.Do()
 	if err != nil {
+		log.Printf("Http Send: Error [%v]", err)
 		return nil, err
 	}
-	return data, nil
+	log.Printf("Http Send: code [%d], data [%d], elapsed [%s]", resp.StatusCode, len(resp.Body), resp.Elapsed)
+	return resp, nil
 }
 
 //ErrResponse is struct used to parse response body
@@ -275,49 +274,58 @@ type ErrResponse struct {
 	Message string `json:"message"`
 }
 
+//errHandler is a rest.ErrorHandler for the Widget API
+type errHandler struct{}
+
+//Handle implements rest.ErrorHandler using handleError
+func (eh errHandler) Handle(code int, data []byte) error {
+	return handleError(code, data)
+}
+
 //handleError takes in response code and response body in bytes
 //and determines if there is a 'soft' error in what the service
 //returned. Basically determines if code is something that can be
 // linked with an error. Then checks the message response for json
 //error message. Returns nil error if the codes and response message
-//checks out, otherwise returns error per error formatting in the
-//message itself.
+//checks out, otherwise returns a *rest.HTTPError per error formatting
+//in the message itself.
 func handleError(code int, data []byte) (err error) {
 
-	if code == 200 {
-		return nil
-	}
 	//no special handling based on body
 	if len(data) < 1 {
-		return nil
+		if code < 300 {
+			return nil
+		}
+		return &rest.HTTPError{StatusCode: code}
 	}
 
 	e := ErrResponse{}
 	json.Unmarshal([]byte(data), &e)
-	log.Printf("Error [%v], Message [%s] --> %d", e.Error, e.Message, len(e.Error))
 
-	if len(e.Error) > 0 {
-		return fmt.Errorf("%d - %s : %s", code, e.Error, e.Message)
+	//a successful status may still carry an error payload
+	if len(e.Error) > 0 || code >= 300 {
+		log.Printf("Error [%v], Message [%s] --> %d", e.Error, e.Message, len(e.Error))
+		return &rest.HTTPError{
+			StatusCode: code,
+			Body:       data,
+			Code:       e.Error,
+			Message:    e.Message,
+		}
 	}
 	return nil
 }
 
-//handleResponse pulls status code and body content from http response
-//if no body returns zero byte array
-func parseResponse(response *http.Response) (code int, data []byte, err error) {
+//widgetNotFound is the message of the service for a Widget that
+//does not exist
+const widgetNotFound = "Widget Not Found"
 
-	//pull out status code
-	code = response.StatusCode
-
-	//get response body if we have one and
-	//defer the body closure
-	if response.Body != nil {
-		defer response.Body.Close()
-		data, err = ioutil.ReadAll(response.Body)
-		if err != nil {
-			return code, nil, fmt.Errorf("Unable to read response body")
-		}
-		return code, data, nil
+//IsNotFound reports whether err is the service reporting
+//that a Widget does not exist, by status 404 or by the
+//message of the error whatever its status
+func IsNotFound(err error) bool {
+	if rest.IsNotFound(err) {
+		return true
 	}
-	return code, nil, nil
+	var he *rest.HTTPError
+	return errors.As(err, &he) && strings.EqualFold(strings.TrimSpace(he.Message), widgetNotFound)
 }
@@ -1,25 +1,68 @@
 package client
 
 import (
-	"encoding/json"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/ericroys/rest"
+	"github.com/ericroys/terraform-provider-widget/widget/fake"
 )
 
+//testClient returns a Client replaying the test's fixture from
+//testdata. The fixtures are written by hand after the Widget API's
+//responses, they are not recordings. With REST_RECORD set in the
+//environment the test records the live service in their place.
+func testClient(t *testing.T) *Client {
+	c, err := NewClient("http://localhost:8080/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mode := rest.ModeReplay
+	if len(os.Getenv("REST_RECORD")) > 0 {
+		mode = rest.ModeRecord
+	}
+	rec, err := rest.NewRecorder(filepath.Join("testdata", t.Name()+".json"), mode, c.httpClient.Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := rec.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+	c.SetTransport(rec)
+	return c
+}
+
 func TestUnmarshalWidget(t *testing.T) {
 
 	in := `{"id": "123", "name": "testWidget", "size": "enourmous", "uid": "f45fdkksj89g"}`
 	inBad := `{"id": "123", "badName": "testWidget", "size": "enourmous", "uid": "f45fdkksj89g"}`
-	w := Widget{}
-
-	err := json.Unmarshal([]byte(in), &w)
+	body := in
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+	get := func() (Widget, error) {
+		b := rest.NewRequestBuilder(ts.URL, ts.Client()).
+			Auth(rest.AuthNoAuth{}).
+			Method(rest.GET)
+		w, _, err := rest.GetJSON[Widget](context.Background(), b, rest.DisallowUnknownFields())
+		return w, err
+	}
 
+	w, err := get()
 	if err != nil {
 		t.Fatal("Failed to unmarshal widget", err)
 	}
 	t.Logf("%v", w)
 
-	err = json.Unmarshal([]byte(inBad), &w)
-
+	body = inBad
+	_, err = get()
 	if err == nil {
 		t.Fatal("Expected error but got none")
 	}
@@ -32,10 +75,7 @@ func TestCreateWidget(t *testing.T) {
 		Size: "not very",
 	}
 
-	c, err := NewClient("http://localhost:8080/api")
-	if err != nil {
-		t.Fatal(err)
-	}
+	c := testClient(t)
 	x, err := c.CreateWidget(w)
 
 	if err != nil {
@@ -49,7 +89,7 @@ func TestCreateWidget(t *testing.T) {
 }
 
 func TestDeleteWidget(t *testing.T) {
-	c, _ := NewClient("http://localhost:8080/api")
+	c := testClient(t)
 	ids := []string{
 		"1", "2", "3", "4", "unknown",
 	}
@@ -69,7 +109,7 @@ func TestDeleteWidget(t *testing.T) {
 }
 
 func TestUpdateWidget(t *testing.T) {
-	c, _ := NewClient("http://localhost:8080/api")
+	c := testClient(t)
 
 	w := WidgetNew{
 		Name: "newWidget1",
@@ -89,7 +129,7 @@ func TestGetWidget(t *testing.T) {
 		"1", "2", "3", "4", "unknown",
 	}
 
-	c, _ := NewClient("http://localhost:8080/api")
+	c := testClient(t)
 
 	for i, id := range ids {
 		x, err := c.GetWidget(id)
@@ -113,3 +153,89 @@ func TestGetWidget(t *testing.T) {
 	//t.Fatalf("RESPONSE: %v", out)
 
 }
+
+func TestHandleError(t *testing.T) {
+	data := `{"timestamp": "2019-05-08T14:12:09.041+0000", "status": 404,
+		"error": "Not Found", "message": "Widget Not Found", "path": "/api/widget/7"}`
+
+	err := handleError(404, []byte(data))
+	if !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+	if err.Error() != "404 - Not Found : Widget Not Found" {
+		t.Fatalf("Unexpected message: %s", err.Error())
+	}
+	if err = handleError(500, nil); err == nil || IsNotFound(err) {
+		t.Fatalf("Expected server error, got: %v", err)
+	}
+	//the message is recognized whatever the status
+	for _, code := range []int{200, 500} {
+		if err = handleError(code, []byte(data)); !IsNotFound(err) {
+			t.Fatalf("Expected not found for %d, got: %v", code, err)
+		}
+	}
+	if err = handleError(500, []byte(`{"error": "Internal Server Error", "message": "Disk full"}`)); IsNotFound(err) {
+		t.Fatalf("Expected server error, got: %v", err)
+	}
+	if err = handleError(200, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateWidgetLocation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			w.Header().Set("Location", "/api/widget/42")
+			w.WriteHeader(http.StatusCreated)
+		case "GET":
+			if r.URL.Path != "/api/widget/42" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"id": "42", "uid": "u42", "name": "located", "size": "small"}`))
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL + "/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := c.CreateWidget(WidgetNew{Name: "located", Size: "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.ID != "42" || x.Name != "located" {
+		t.Fatalf("Unexpected widget: %v", x)
+	}
+}
+
+func TestFakeWidgetLifecycle(t *testing.T) {
+	srv := fake.NewServer()
+	defer srv.Close()
+	c, err := NewClient(srv.BaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := c.CreateWidget(WidgetNew{Name: "w1", Size: "small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, err = c.UpdateWidget(w.ID, WidgetNew{Name: "w1", Size: "large"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := srv.Widget(w.ID); got.Size != "large" {
+		t.Fatalf("Expected updated widget, got: %+v", got)
+	}
+	if err = c.DeleteWidget(w.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.GetWidget(w.ID); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+	if err = c.DeleteWidget(w.ID); !IsNotFound(err) {
+		t.Fatalf("Expected not found, got: %v", err)
+	}
+}
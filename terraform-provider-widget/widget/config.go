@@ -1,6 +1,7 @@
 package widget
 
 import (
+	"github.com/ericroys/rest"
 	"github.com/ericroys/terraform-provider-widget/widget/client"
 )
 
@@ -10,11 +11,41 @@ type Config struct {
 	Password   string
 	ServiceURL string
 	MaxRetries int
+	//TLS settings for the service
+	CAFile         string
+	ClientCertFile string
+	ClientKeyFile  string
+	Insecure       bool
 }
 
 //GetClient returns an initialized api client
 func (c *Config) GetClient() (interface{}, error) {
 	client, err := client.NewClient(c.ServiceURL)
 	//client := Client{}.NewClient(c)
-	return client, err
+	if err != nil {
+		return nil, err
+	}
+	client.SetMaxRetries(c.MaxRetries)
+	err = client.SetTLSOptions(rest.TLSOptions{
+		CAFiles:            files(c.CAFile),
+		CertFile:           c.ClientCertFile,
+		KeyFile:            c.ClientKeyFile,
+		InsecureSkipVerify: c.Insecure,
+	})
+	if err != nil {
+		return nil, err
+	}
+	//basic authentication when credentials are configured
+	if len(c.Username) > 0 {
+		client.SetAuthenticator(rest.NewAuthBasic(c.Username, c.Password))
+	}
+	return client, nil
+}
+
+//files returns the file as a list, or nil if not set
+func files(f string) []string {
+	if len(f) == 0 {
+		return nil
+	}
+	return []string{f}
 }
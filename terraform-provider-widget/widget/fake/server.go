@@ -0,0 +1,204 @@
+//Package fake provides an in-process fake of the Widget API for
+//testing the client and the provider without the Widget service.
+//
+//Widgets are kept in memory. Errors are returned with the Spring
+//style payload of the real service, e.g. a 404 with the message
+//"Widget Not Found".
+//
+//  srv := fake.NewServer()
+//  defer srv.Close()
+//  c, err := client.NewClient(srv.BaseURL())
+package fake
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Widget is a widget stored by the fake
+type Widget struct {
+	ID   string `json:"id"`
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+	Size string `json:"size"`
+}
+
+//springError is the error payload of the Widget service
+type springError struct {
+	Timestamp string `json:"timestamp"`
+	Status    int    `json:"status"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Path      string `json:"path"`
+}
+
+//Server is a fake Widget API server. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	widgets map[string]Widget
+	next    int
+}
+
+//NewServer starts a Server without widgets. The caller should call
+//Close when finished.
+func NewServer() *Server {
+	s := &Server{widgets: make(map[string]Widget)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+//BaseURL returns the url of the Widget API for use as the client's
+//base url
+func (s *Server) BaseURL() string {
+	return s.URL + "/api"
+}
+
+//Seed stores a widget and returns it with its id and uid set
+func (s *Server) Seed(name, size string) Widget {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.create(name, size)
+}
+
+//Widget returns the widget with id
+func (s *Server) Widget(id string) (Widget, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.widgets[id]
+	return w, ok
+}
+
+//Widgets returns all widgets ordered by id
+func (s *Server) Widgets() []Widget {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list()
+}
+
+//serve handles /api/widget and /api/widget/{id}
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case p == "/api/widget":
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.list())
+		case http.MethodPost:
+			in, ok := s.decode(w, r)
+			if !ok {
+				return
+			}
+			nw := s.create(in.Name, in.Size)
+			w.Header().Set("Location", s.BaseURL()+"/widget/"+nw.ID)
+			writeJSON(w, http.StatusCreated, nw)
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, "Request method '"+r.Method+"' not supported")
+		}
+	case strings.HasPrefix(p, "/api/widget/"):
+		id := strings.TrimPrefix(p, "/api/widget/")
+		cur, ok := s.widgets[id]
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "Widget Not Found")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, cur)
+		case http.MethodPost, http.MethodPut:
+			in, ok := s.decode(w, r)
+			if !ok {
+				return
+			}
+			cur.Name, cur.Size = in.Name, in.Size
+			s.widgets[id] = cur
+			writeJSON(w, http.StatusOK, cur)
+		case http.MethodDelete:
+			delete(s.widgets, id)
+			w.WriteHeader(http.StatusOK)
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, "Request method '"+r.Method+"' not supported")
+		}
+	default:
+		writeError(w, r, http.StatusNotFound, "No message available")
+	}
+}
+
+//decode reads a widget from the request body, writing a
+//400 response if it is not valid
+func (s *Server) decode(w http.ResponseWriter, r *http.Request) (Widget, bool) {
+	var in Widget
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, r, http.StatusBadRequest, "JSON parse error: "+err.Error())
+		return in, false
+	}
+	if len(in.Name) == 0 {
+		writeError(w, r, http.StatusBadRequest, "Widget name is required")
+		return in, false
+	}
+	return in, true
+}
+
+//create stores a new widget
+func (s *Server) create(name, size string) Widget {
+	s.next++
+	nw := Widget{
+		ID:   strconv.Itoa(s.next),
+		UID:  newUID(),
+		Name: name,
+		Size: size,
+	}
+	s.widgets[nw.ID] = nw
+	return nw
+}
+
+//list returns the widgets ordered by id
+func (s *Server) list() []Widget {
+	out := make([]Widget, 0, len(s.widgets))
+	for _, w := range s.widgets {
+		out = append(out, w)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, _ := strconv.Atoi(out[i].ID)
+		b, _ := strconv.Atoi(out[j].ID)
+		return a < b
+	})
+	return out
+}
+
+//writeError writes a Spring style error
+func writeError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	writeJSON(w, status, springError{
+		Timestamp: time.Now().UTC().Format("2006-01-02T15:04:05.000+0000"),
+		Status:    status,
+		Error:     http.StatusText(status),
+		Message:   msg,
+		Path:      r.URL.Path,
+	})
+}
+
+//writeJSON writes v as the json response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+//newUID returns a random uuid
+func newUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,42 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNotFound(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.Seed("w1", "small")
+
+	resp, err := http.Get(srv.BaseURL() + "/widget/7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var e springError
+	json.NewDecoder(resp.Body).Decode(&e)
+	if resp.StatusCode != 404 || e.Error != "Not Found" || e.Message != "Widget Not Found" || e.Path != "/api/widget/7" {
+		t.Fatalf("Unexpected response: %d %+v", resp.StatusCode, e)
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	resp, err := http.Post(srv.BaseURL()+"/widget", "application/json", strings.NewReader(`{"size": "small"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 400 {
+		t.Fatalf("Expected bad request, got: %d", resp.StatusCode)
+	}
+	if len(srv.Widgets()) != 0 {
+		t.Fatal("Expected no widget to be created")
+	}
+}
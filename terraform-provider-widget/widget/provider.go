@@ -38,6 +38,31 @@ func Provider() terraform.ResourceProvider {
 				Default:     25,
 				Description: "max_retries",
 			},
+
+			"ca_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "PEM file of additional CA certificates to trust",
+			},
+
+			"client_cert_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "PEM client certificate file for mutual TLS",
+			},
+
+			"client_key_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "PEM client key file for mutual TLS",
+			},
+
+			"insecure": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "accept any server certificate",
+			},
 		},
 
 		DataSourcesMap: map[string]*schema.Resource{
@@ -59,6 +84,11 @@ func providerConfigure(d *schema.ResourceData) (interface{}, error) {
 		Password:   d.Get("password").(string),
 		ServiceURL: d.Get("service_url").(string),
 		MaxRetries: d.Get("max_retries").(int),
+
+		CAFile:         d.Get("ca_file").(string),
+		ClientCertFile: d.Get("client_cert_file").(string),
+		ClientKeyFile:  d.Get("client_key_file").(string),
+		Insecure:       d.Get("insecure").(bool),
 	}
 	c, err := config.GetClient()
 	if err != nil {
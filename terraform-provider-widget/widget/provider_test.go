@@ -4,10 +4,29 @@ import (
 	"os"
 	"testing"
 
+	"github.com/ericroys/terraform-provider-widget/widget/fake"
+	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 )
 
+//TestMain runs the acceptance tests against a fake Widget service
+//unless SERVICE_BASEURL points to a real one. Acceptance tests are
+//enabled for the fake since they create nothing outside the process.
+func TestMain(m *testing.M) {
+	if _, ok := os.LookupEnv("SERVICE_BASEURL"); ok {
+		os.Exit(m.Run())
+	}
+	srv := fake.NewServer()
+	os.Setenv("SERVICE_BASEURL", srv.BaseURL())
+	if _, ok := os.LookupEnv(resource.TestEnvVar); !ok {
+		os.Setenv(resource.TestEnvVar, "1")
+	}
+	code := m.Run()
+	srv.Close()
+	os.Exit(code)
+}
+
 func TestProvider(t *testing.T) {
 	if err := Provider().(*schema.Provider).InternalValidate(); err != nil {
 		t.Fatalf("err: %s", err)
@@ -2,7 +2,6 @@ package widget
 
 import (
 	"log"
-	"strings"
 
 	"github.com/ericroys/terraform-provider-widget/widget/client"
 
@@ -111,7 +110,7 @@ func resourceWidgetDelete(d *schema.ResourceData, m interface{}) error {
 	//delete the widget by id
 	err := c.DeleteWidget(d.Id())
 	//return error unless widget isn't found
-	if err != nil && !strings.Contains(err.Error(), "Widget Not Found") {
+	if err != nil && !client.IsNotFound(err) {
 		return err
 	}
 	//set resource id to empty
@@ -2,7 +2,6 @@ package widget
 
 import (
 	"fmt"
-	"strings"
 	"testing"
 
 	"github.com/ericroys/terraform-provider-widget/widget/client"
@@ -17,15 +16,32 @@ func TestAccWidget_Basic(t *testing.T) {
 		CheckDestroy: testAccCheckWidgetDestroy,
 		Steps: []resource.TestStep{
 			{
-				Config: testAccCheckWidget(t),
+				Config: testAccCheckWidget(t, "terraformTest", "momentous occasion"),
 				Check: resource.ComposeTestCheckFunc(
 					testAccCheckWidgetExists("widget_widget.test", t),
 					resource.TestCheckResourceAttr(
 						"widget_widget.test", "name", "terraformTest"),
 					resource.TestCheckResourceAttr(
 						"widget_widget.test", "size", "momentous occasion"),
+					resource.TestCheckResourceAttrSet(
+						"widget_widget.test", "uid"),
 				),
 			},
+			{
+				Config: testAccCheckWidget(t, "terraformTest2", "slightly smaller"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckWidgetExists("widget_widget.test", t),
+					resource.TestCheckResourceAttr(
+						"widget_widget.test", "name", "terraformTest2"),
+					resource.TestCheckResourceAttr(
+						"widget_widget.test", "size", "slightly smaller"),
+				),
+			},
+			{
+				ResourceName:      "widget_widget.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 		},
 	})
 }
@@ -64,7 +80,7 @@ func testAccCheckWidgetDestroy(s *terraform.State) error {
 		if err == nil {
 			return fmt.Errorf("Widget still exists")
 		}
-		if !strings.Contains(err.Error(), "Widget Not Found") {
+		if !client.IsNotFound(err) {
 			return err
 		}
 	}
@@ -72,11 +88,11 @@ func testAccCheckWidgetDestroy(s *terraform.State) error {
 	return nil
 }
 
-func testAccCheckWidget(t *testing.T) string {
+func testAccCheckWidget(t *testing.T, name, size string) string {
 	t.Log("Get Widget Resource - OK")
 	return fmt.Sprintf(
 		`resource "widget_widget" "test"{
-			name = "terraformTest"
-			size = "momentous occasion"
-		  }`)
+			name = "%s"
+			size = "%s"
+		  }`, name, size)
 }